@@ -0,0 +1,174 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nukleros/gener8s/internal/directive"
+	"github.com/nukleros/gener8s/internal/options"
+	"github.com/nukleros/gener8s/internal/output"
+	"github.com/nukleros/gener8s/pkg/generate/code"
+	"github.com/nukleros/gener8s/pkg/generate/naming"
+	"github.com/nukleros/gener8s/pkg/manifests"
+)
+
+// GenCommand creates the gen subcommand, which generates objects into go
+// packages from the directives in their source files.
+func (r *Root) GenCommand() *cobra.Command {
+	cliOptions := &options.DirectiveOptions{}
+
+	genCmd := &cobra.Command{
+		Use:   "gen [packages]",
+		Short: "Generate objects into go packages from directives in their source files",
+		Long: `Scan go packages for directives of the form:
+
+    //gener8s:object manifests/deploy.yaml [more manifests...] [key=value...]
+
+and generate the objects of the manifests into a ` + directive.GeneratedFilename + ` file in
+the same package.  Manifest paths are relative to the directory of the source file, and may
+include globbing.  The supported keys are:
+
+    var          name of the variable, or constructor, of a single object, or the
+                 prefix of the names of multiple objects
+    constructor  generate constructor functions rather than variables
+    values       yaml file with values to insert into fields with !!tpl tags
+    kind, name, namespace, selector
+                 only generate the selected objects, as with the go command
+
+Packages are given as directories, where a trailing /... also matches all directories below
+it, and default to the current directory.  With a //go:generate gener8s gen directive in each
+package, regenerating is just go generate ./...`,
+		Example: `
+# generate objects for the package in the current directory, such as from go generate
+//go:generate gener8s gen
+
+# generate objects for all packages in a module
+gener8s gen ./...
+
+# check that the generated objects of all packages are up to date, such as in CI
+gener8s gen --check ./...
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dirs, err := directive.Dirs(args...)
+			if err != nil {
+				return fmt.Errorf("%w", err)
+			}
+
+			// when checking, all packages are checked so that every stale file is reported
+			var stale []string
+
+			for _, dir := range dirs {
+				pkg, err := directive.Parse(dir)
+				if err != nil {
+					return fmt.Errorf("%w", err)
+				}
+
+				if pkg == nil || len(pkg.Directives) == 0 {
+					continue
+				}
+
+				source, err := generatePackage(pkg, cliOptions.NamePattern)
+				if err != nil {
+					return err
+				}
+
+				err = output.Update(filepath.Join(dir, directive.GeneratedFilename), source, cliOptions.Check)
+
+				switch {
+				case cliOptions.Check && errors.Is(err, output.ErrOutOfDate):
+					stale = append(stale, dir)
+				case err != nil:
+					return fmt.Errorf("%w", err)
+				}
+			}
+
+			if len(stale) > 0 {
+				return fmt.Errorf("%w; packages %s", output.ErrOutOfDate, strings.Join(stale, ", "))
+			}
+
+			return nil
+		},
+	}
+
+	genCmd.Flags().StringVar(
+		&cliOptions.NamePattern,
+		"name-pattern",
+		naming.DefaultPattern,
+		"template of the names of the generated variables, or constructors, of objects not named with var, from the "+
+			".Kind, .Name, .Namespace, .Group and .Version of each object; names are made unique within each package "+
+			"with a numeric suffix",
+	)
+
+	genCmd.Flags().BoolVar(
+		&cliOptions.Check,
+		"check",
+		false,
+		"check that the generated files are up to date, printing a diff of those which are not, without writing them",
+	)
+
+	return genCmd
+}
+
+// generatePackage returns the go source file with the objects of all of the
+// directives of a package, which are named with a naming pattern so that their
+// names are unique within the package.
+func generatePackage(pkg *directive.Package, namePattern string) (string, error) {
+	var source strings.Builder
+
+	imports := map[string]bool{}
+
+	namer, err := naming.New(namePattern)
+	if err != nil {
+		return "", fmt.Errorf("%w", err)
+	}
+
+	for _, objectDirective := range pkg.Directives {
+		directiveOptions := objectDirective.Options()
+
+		values, err := directiveOptions.ReadValues()
+		if err != nil {
+			return "", fmt.Errorf("%w; directive at %s:%d", err, objectDirective.Filename, objectDirective.Line)
+		}
+
+		files, skipped, err := directiveOptions.LoadManifests()
+		if err != nil {
+			return "", fmt.Errorf("%w; directive at %s:%d", err, objectDirective.Filename, objectDirective.Line)
+		}
+
+		if len(skipped) > 0 {
+			os.Stderr.WriteString(manifests.Summary(skipped))
+		}
+
+		objectImports, err := code.WriteObjects(context.Background(), &source, files, &code.ObjectOptions{
+			Values:      values,
+			Name:        objectDirective.Var,
+			Constructor: directiveOptions.Constructor,
+			Namer:       namer,
+		})
+		if err != nil {
+			return "", fmt.Errorf("%w; directive at %s:%d", err, objectDirective.Filename, objectDirective.Line)
+		}
+
+		for _, path := range objectImports {
+			imports[path] = true
+		}
+	}
+
+	paths := []string{code.UnstructuredImport}
+	for path := range imports {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	return output.GoFile(pkg.Name, source.String(), paths...)
+}
@@ -0,0 +1,155 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+package command
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nukleros/gener8s/internal/command/rbac"
+	"github.com/nukleros/gener8s/internal/config"
+	"github.com/nukleros/gener8s/internal/options"
+	"github.com/nukleros/gener8s/internal/output"
+	"github.com/nukleros/gener8s/internal/watch"
+)
+
+// GenerateJobsCommand creates the generate subcommand, which runs the jobs
+// declared in the project config file.
+func (r *Root) GenerateJobsCommand() *cobra.Command {
+	cliOptions := &options.ConfigOptions{}
+
+	generateCmd := &cobra.Command{
+		Use:   "generate [job...]",
+		Short: "Run the generation jobs declared in the project config file",
+		Long: `Run all of the generation jobs declared in the project config file, or only
+the named jobs, in order.  Each job declares its inputs, values, mode (go, rbac-yaml,
+rbac-markers or rbac-go), output path, go package and options.`,
+		Example: `
+# run all jobs declared in gener8s.yaml
+gener8s generate
+
+# run only the named jobs from a config file in another directory
+gener8s generate --config /path/to/gener8s.yaml webstore rbac
+
+# check that the outputs of all jobs are up to date, such as in CI
+gener8s generate --check
+
+# rerun each job whenever its manifests or values change
+gener8s generate --watch
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectConfig, err := config.Load(cliOptions.ConfigFilepath)
+			if err != nil {
+				return fmt.Errorf("%w", err)
+			}
+
+			jobs, err := projectConfig.Select(args...)
+			if err != nil {
+				return fmt.Errorf("%w", err)
+			}
+
+			if cliOptions.Watch {
+				targets := make([]*watch.Target, 0, len(jobs))
+
+				for _, job := range jobs {
+					job := job
+					inputOptions := projectConfig.InputOptions(job)
+					targets = append(targets, &watch.Target{
+						Name:   job.Name,
+						Inputs: inputOptions.Inputs,
+						Run:    func() error { return runJob(projectConfig, job, cliOptions.Check, nil) },
+					})
+				}
+
+				return watch.Run(targets...)
+			}
+
+			// the files which are no longer generated are found once all of the jobs
+			// are run, as jobs may share an output directory
+			sets := output.NewSets()
+
+			// when checking, all jobs are checked so that every stale output is reported
+			var stale []string
+
+			for _, job := range jobs {
+				err := runJob(projectConfig, job, cliOptions.Check, sets)
+
+				switch {
+				case cliOptions.Check && errors.Is(err, output.ErrOutOfDate):
+					stale = append(stale, job.Name)
+				case err != nil:
+					return fmt.Errorf("%w; failed to run job %s", err, job.Name)
+				}
+			}
+
+			if !cliOptions.Check {
+				return sets.Record()
+			}
+
+			removed := sets.Check(os.Stdout)
+			if removed != nil && !errors.Is(removed, output.ErrOutOfDate) {
+				return removed
+			}
+
+			if len(stale) > 0 {
+				return fmt.Errorf("%w; jobs %s", output.ErrOutOfDate, strings.Join(stale, ", "))
+			}
+
+			return removed
+		},
+	}
+
+	generateCmd.Flags().StringVarP(
+		&cliOptions.ConfigFilepath,
+		"config",
+		"c",
+		config.DefaultFilename,
+		"path to the project config file declaring the generation jobs",
+	)
+
+	generateCmd.Flags().BoolVar(
+		&cliOptions.Check,
+		"check",
+		false,
+		"check that the outputs of the jobs are up to date, printing a diff of those which are not, without writing them",
+	)
+
+	generateCmd.Flags().BoolVar(
+		&cliOptions.Watch,
+		"watch",
+		false,
+		"rerun each job whenever its manifests or values change, until interrupted; changes to the config file require a restart",
+	)
+
+	return generateCmd
+}
+
+// runJob runs a single job, writing its output as specified by the job, or
+// checking that it is up to date, and adds the files of its output directory to
+// the sets when given.
+func runJob(projectConfig *config.Config, job *config.Job, check bool, sets *output.Sets) error {
+	if job.Mode == config.ModeGo {
+		goOptions := projectConfig.GoOptions(job)
+		goOptions.Check = check
+
+		return generateGo(goOptions, sets)
+	}
+
+	jobOptions := projectConfig.RBACOptions(job)
+	jobOptions.Check = check
+
+	switch job.Mode {
+	case config.ModeRBACYAML:
+		return rbac.Generate(jobOptions, options.WithYAML, sets)
+	case config.ModeRBACMarkers:
+		return rbac.Generate(jobOptions, options.WithMarkers, sets)
+	case config.ModeRBACGo:
+		return rbac.Generate(jobOptions, options.WithGo, sets)
+	}
+
+	return nil
+}
@@ -3,18 +3,25 @@
 package command
 
 import (
+	"context"
 	"fmt"
 	"os"
 
+	"github.com/iancoleman/strcase"
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
 
+	"github.com/nukleros/gener8s/internal/options"
+	"github.com/nukleros/gener8s/internal/output"
+	"github.com/nukleros/gener8s/internal/watch"
 	"github.com/nukleros/gener8s/pkg/generate/code"
+	"github.com/nukleros/gener8s/pkg/generate/naming"
 	"github.com/nukleros/gener8s/pkg/manifests"
 )
 
 // GenerateGoCommand creates the generate subcommand.
 func (r *Root) GenerateGoCommand() *cobra.Command {
+	cliOptions := &options.GoOptions{OutputOptions: options.OutputOptions{Name: "go"}}
+
 	generateCmd := &cobra.Command{
 		Use:   "go",
 		Short: "Generate Go source code for Kubernetes object from yaml",
@@ -23,70 +30,232 @@ object and get source code for an unstructured Kubernetes object type.`,
 		Example: `
 # generate unstructured go code for a kubernetes object
 gener8s go -m /path/to/rbac.yaml
-`,
-		RunE: func(cmd *cobra.Command, args []string) error {
 
-			var values map[string]interface{}
+# generate unstructured go code for objects read from standard input
+kubectl get deployment webstore -o yaml | gener8s go -m -
 
-			if r.Options.ValuesFilePath != "" {
-				valuesFile, vErr := os.ReadFile(r.Options.ValuesFilePath)
-				if vErr != nil {
-					return fmt.Errorf("%w", vErr)
-				}
+# generate unstructured go code for all manifests in a directory tree, other than test fixtures
+gener8s go -m 'config/**/*.yaml' --exclude 'testdata/' --skip-non-objects
 
-				if vErr := yaml.Unmarshal(valuesFile, &values); vErr != nil {
-					return fmt.Errorf("%w", vErr)
-				}
-			}
+# generate unstructured go code for a base and an overlay, merging the objects defined in both
+gener8s go -m 'base/*.yaml' -m 'overlays/prod/*.yaml' --duplicates merge
 
-			manifests, err := manifests.ExpandManifests("", r.Options.ManifestFilepaths)
-			if err != nil {
-				return fmt.Errorf("%w", err)
-			}
+# generate unstructured go code for only the deployments labeled as the frontend tier
+gener8s go -m /path/to/manifests --kind Deployment -l tier=frontend
 
-			// load manifest content for each manifest
-			for _, manifest := range *manifests {
-				if err = manifest.LoadContent(); err != nil {
-					return fmt.Errorf("%w", err)
-				}
-			}
+# generate a go source file for each object, in the webstore package
+gener8s go -m /path/to/manifests --output-dir pkg/webstore --package webstore
 
-			source, err := code.GenerateCode(manifests, r.Options, values)
-			if err != nil {
-				return fmt.Errorf("%w", err)
-			}
+# generate a go source file for each manifest file, named after the manifest file
+gener8s go -m /path/to/manifests --output-dir pkg/webstore --package webstore --group-by-source \
+    --filename-template 'zz_generated.{{ .Source }}.go'
+
+# check that the generated go source files are up to date, such as in CI
+gener8s go -m /path/to/manifests --output-dir pkg/webstore --package webstore --check
+
+# generate a function returning all of the objects in install order, and another in teardown order
+gener8s go -m /path/to/manifests --output-dir pkg/webstore --package webstore --collection Objects
+
+# regenerate the go source files whenever the manifests or values change
+gener8s go -m '/path/to/manifests/*.yaml' -f values.yaml --output-dir pkg/webstore --package webstore --watch
 
-			os.Stdout.WriteString(source)
+# generate unstructured go code for the objects rendered from a kustomization
+gener8s go --kustomize /path/to/overlay
+
+# generate unstructured go code for the objects rendered from a local helm chart
+gener8s go --chart /path/to/chart -f /path/to/values.yaml
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cliOptions.Watch {
+				return watch.Run(&watch.Target{
+					Name:   "go",
+					Inputs: cliOptions.Inputs,
+					Run:    func() error { return generateGo(cliOptions, nil) },
+				})
+			}
 
-			return nil
+			return generateGo(cliOptions, nil)
 		},
 	}
 
-	generateCmd.Flags().StringArrayVarP(
-		&r.Options.ManifestFilepaths,
-		"manifest-files",
-		"m",
-		[]string{},
-		"path to manifest files containing resource definition; may include globbing",
-	)
+	options.AddInputFlags(generateCmd, &cliOptions.InputOptions)
 
 	generateCmd.Flags().StringVarP(
-		&r.Options.VariableName,
+		&cliOptions.VariableName,
 		"variable-name",
 		"v",
 		"object",
 		"variable name for resource object",
 	)
 
+	generateCmd.Flags().StringVar(
+		&cliOptions.NamePattern,
+		"name-pattern",
+		naming.DefaultPattern,
+		"template of the names of the generated variables, or constructors, from the .Kind, .Name, .Namespace, .Group "+
+			"and .Version of each object; names are made unique with a numeric suffix",
+	)
+
+	generateCmd.Flags().BoolVar(
+		&cliOptions.Constructor,
+		"constructor",
+		false,
+		"generate constructor functions, with typed variable references as parameters, instead of variables",
+	)
+
+	generateCmd.Flags().StringVar(
+		&cliOptions.Collection,
+		"collection",
+		"",
+		"name of a function to generate which returns all of the objects as a []client.Object in install order, "+
+			"along with a function suffixed with Teardown which returns them in reverse; written to its own file with --output-dir",
+	)
+
+	generateCmd.Flags().StringSliceVar(
+		&cliOptions.KindOrder,
+		"kind-order",
+		nil,
+		"order in which objects of the collection are installed by kind, where * places any kinds which are not listed "+
+			"(default the install order of helm, with admission webhooks last)",
+	)
+
 	generateCmd.Flags().StringVarP(
-		&r.Options.ValuesFilePath,
-		"values-file",
-		"f",
+		&cliOptions.OutputFile,
+		"output",
+		"o",
 		"",
-		"yaml file with values to insert into fields with !!tpl tags",
+		"file to write the generated code to, rather than standard output",
 	)
 
-	cobra.CheckErr(generateCmd.MarkFlagRequired("manifest-files"))
+	generateCmd.Flags().StringVar(
+		&cliOptions.OutputDir,
+		"output-dir",
+		"",
+		"directory to write the generated code to, with a file for each object, or for each manifest file with --group-by-source",
+	)
+
+	generateCmd.Flags().StringVar(
+		&cliOptions.OutputTemplate,
+		"filename-template",
+		"",
+		"template for the names of the files written to --output-dir "+
+			"(default \"{{ with .Namespace }}{{ . }}_{{ end }}{{ .Kind | lower }}_{{ .Name }}.go\", or \"{{ .Source }}.go\" with --group-by-source)",
+	)
+
+	generateCmd.Flags().BoolVar(
+		&cliOptions.GroupBySource,
+		"group-by-source",
+		false,
+		"write a file for each manifest file, rather than for each object, to --output-dir",
+	)
+
+	generateCmd.Flags().StringVar(
+		&cliOptions.Package,
+		"package",
+		"",
+		"go package of the generated code; when given, complete go source files are generated",
+	)
+
+	generateCmd.Flags().BoolVar(
+		&cliOptions.Check,
+		"check",
+		false,
+		"check that the output files are up to date, printing a diff of those which are not, without writing them",
+	)
+
+	generateCmd.Flags().BoolVar(
+		&cliOptions.Watch,
+		"watch",
+		false,
+		"regenerate the output whenever the manifests or values change, until interrupted",
+	)
 
 	return generateCmd
 }
+
+// generateGo loads the manifests for a set of options and writes the
+// unstructured go code for them as specified by the output options, adding the
+// files of an output directory to the sets when given.  A summary of any
+// skipped manifests is written to standard error.
+func generateGo(cliOptions *options.GoOptions, sets *output.Sets) error {
+	values, err := cliOptions.ReadValues()
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	files, skipped, err := cliOptions.LoadManifests()
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	if len(skipped) > 0 {
+		os.Stderr.WriteString(manifests.Summary(skipped))
+	}
+
+	// objects written to several files of a package are named by a single namer,
+	// so that their names are unique within the package
+	namer, err := naming.New(cliOptions.NamePattern)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	var collection *code.Collection
+	if cliOptions.Collection != "" {
+		collection = &code.Collection{Name: cliOptions.Collection, KindOrder: cliOptions.KindOrder}
+	}
+
+	generate := func(files *manifests.Manifests) (*output.Output, error) {
+		objects, err := code.GenerateObjects(context.Background(), files, &code.ObjectOptions{
+			Values:      values,
+			Constructor: cliOptions.Constructor,
+			Namer:       namer,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+
+		source := code.Join(objects)
+
+		if collection != nil {
+			collection.Add(objects...)
+
+			// a single output includes the collection, otherwise it is written to
+			// its own file once all of the objects are generated
+			if cliOptions.OutputDir == "" {
+				collectionSource, err := collection.Source()
+				if err != nil {
+					return nil, fmt.Errorf("%w", err)
+				}
+
+				source.Append(collectionSource)
+			}
+		}
+
+		return goOutput(source, code.UnstructuredImport), nil
+	}
+
+	var extras []output.Extra
+
+	if collection != nil {
+		extras = append(extras, func() (string, *output.Output, error) {
+			source, err := collection.Source()
+			if err != nil {
+				return "", nil, fmt.Errorf("%w", err)
+			}
+
+			return strcase.ToSnake(collection.Name) + ".go", goOutput(source), nil
+		})
+	}
+
+	return output.Write(&cliOptions.OutputOptions, sets, files, values, generate, ".go", extras...)
+}
+
+// goOutput returns the output of generated go source code, which requires the
+// imports of the source along with any others given.
+func goOutput(source *code.Source, imports ...string) *output.Output {
+	return &output.Output{
+		Content: source.String(),
+		Code:    source.Code,
+		Imports: append(imports, source.Imports...),
+	}
+}
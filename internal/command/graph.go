@@ -0,0 +1,114 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nukleros/gener8s/internal/options"
+	"github.com/nukleros/gener8s/internal/output"
+	"github.com/nukleros/gener8s/pkg/graph"
+	"github.com/nukleros/gener8s/pkg/manifests"
+)
+
+// GraphCommand creates the graph subcommand.
+func (r *Root) GraphCommand() *cobra.Command {
+	cliOptions := &options.GraphOptions{}
+
+	graphCmd := &cobra.Command{
+		Use:   "graph",
+		Short: "Graph the references between the objects of a set of manifests",
+		Long: `Pass a set of Kubernetes manifest files and get the graph of the references
+between their objects, such as from pod templates to config maps, secrets, service accounts
+and persistent volume claims, from role bindings to roles, from service selectors to
+workloads, from ingresses to services and from webhooks to services.  References to objects
+which are not in the manifests are dangling, and are summarised on stderr.`,
+		Example: `
+# render the graph of the references between objects with graphviz
+gener8s graph -m 'config/**/*.yaml' | dot -Tsvg > graph.svg
+
+# write the graph as json
+gener8s graph -m 'config/**/*.yaml' --format json -o graph.json
+
+# fail when any object references an object which is not in the manifests, such as in CI
+gener8s graph --kustomize config/default --fail-on-dangling > /dev/null
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return writeGraph(cliOptions)
+		},
+	}
+
+	options.AddInputFlags(graphCmd, &cliOptions.InputOptions)
+
+	graphCmd.Flags().StringVar(
+		&cliOptions.Format,
+		"format",
+		string(graph.FormatDOT),
+		"format of the graph, either dot, for graphviz, or json",
+	)
+
+	graphCmd.Flags().StringVarP(
+		&cliOptions.OutputFile,
+		"output",
+		"o",
+		"",
+		"file to write the graph to, rather than standard output",
+	)
+
+	graphCmd.Flags().BoolVar(
+		&cliOptions.FailOnDangling,
+		"fail-on-dangling",
+		false,
+		"exit non-zero when any object references an object which is not in the manifests",
+	)
+
+	return graphCmd
+}
+
+// writeGraph loads the manifests for a set of options and writes the graph of
+// the references between their objects to the output file, or to standard
+// output when none is given.  Summaries of any skipped manifests, and of any
+// dangling references, are written to standard error.
+func writeGraph(cliOptions *options.GraphOptions) error {
+	files, skipped, err := cliOptions.LoadManifests()
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	if len(skipped) > 0 {
+		os.Stderr.WriteString(manifests.Summary(skipped))
+	}
+
+	references, err := graph.Build(files)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	var content bytes.Buffer
+	if err := references.Write(&content, graph.Format(cliOptions.Format)); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	if cliOptions.OutputFile == "" {
+		os.Stdout.Write(content.Bytes())
+	} else if err := output.WriteFile(cliOptions.OutputFile, content.Bytes()); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	summary := references.Summary()
+	if summary == "" {
+		return nil
+	}
+
+	os.Stderr.WriteString(summary)
+
+	if cliOptions.FailOnDangling {
+		return fmt.Errorf("%w; %d found", graph.ErrDanglingReferences, len(references.Dangling()))
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+package command
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nukleros/gener8s/internal/options"
+	"github.com/nukleros/gener8s/internal/output"
+	"github.com/nukleros/gener8s/internal/plugin"
+	"github.com/nukleros/gener8s/pkg/manifests"
+)
+
+// reservedCommands are the commands which cobra adds to the root command when
+// it is executed, and which plugins therefore may not shadow.
+var reservedCommands = []string{"help", "completion"}
+
+// PluginsCommand creates the plugins subcommand, which lists the generator
+// plugins discovered on the PATH.
+func (r *Root) PluginsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "plugins",
+		Short: "List the generator plugins discovered on the PATH",
+		Long: `List the generator plugins discovered on the PATH.  Any executable named
+` + plugin.Prefix + `<name> is a plugin, which is run as the subcommand <name>.  As with kubectl
+plugins, the first executable on the PATH with a name shadows any others, and plugins
+may not shadow the commands of gener8s itself.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			plugins := plugin.Discover(os.Getenv("PATH"))
+
+			writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(writer, "NAME\tPATH")
+
+			for _, discovered := range plugins {
+				fmt.Fprintf(writer, "%s\t%s\n", discovered.Name, discovered.Path)
+			}
+
+			return writer.Flush()
+		},
+	}
+}
+
+// AddPluginCommand adds the subcommand for the generator plugin named by the
+// first of the arguments, when it is not an existing command, looking up only
+// that plugin on the PATH as kubectl does.  Flags before the subcommand, or no
+// plugin with the name, leave the commands unchanged.
+func (r *Root) AddPluginCommand(args []string) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return
+	}
+
+	name := args[0]
+
+	for _, reserved := range reservedCommands {
+		if name == reserved {
+			return
+		}
+	}
+
+	for _, cmd := range r.Command.Commands() {
+		if cmd.Name() == name || cmd.HasAlias(name) {
+			return
+		}
+	}
+
+	if generator, ok := plugin.Lookup(name); ok {
+		r.Command.AddCommand(r.PluginCommand(generator))
+	}
+}
+
+// PluginCommand creates the subcommand for a generator plugin.
+func (r *Root) PluginCommand(generator *plugin.Plugin) *cobra.Command {
+	cliOptions := &options.PluginOptions{}
+
+	pluginCmd := &cobra.Command{
+		Use:   generator.Name + " [flags] [-- plugin arguments]",
+		Short: fmt.Sprintf("Generate files with the %s plugin (%s)", generator.Name, generator.Path),
+		Long: `Load the manifests and pass their objects, along with their positions and the values,
+as json to the standard input of the plugin, then write the files which the plugin returns as
+json on its standard output to the output directory.  Any arguments after -- are passed to the
+plugin.`,
+		Args: cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPlugin(cmd.Context(), generator, cliOptions, args)
+		},
+	}
+
+	addPluginFlags(pluginCmd, cliOptions)
+
+	return pluginCmd
+}
+
+// runPlugin loads the manifests for a set of options and runs a plugin for
+// them, writing or checking the files it generates.  A summary of any skipped
+// manifests is written to standard error.
+func runPlugin(ctx context.Context, generator *plugin.Plugin, cliOptions *options.PluginOptions, args []string) error {
+	values, err := cliOptions.ReadValues()
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	files, skipped, err := cliOptions.LoadManifests()
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	if len(skipped) > 0 {
+		os.Stderr.WriteString(manifests.Summary(skipped))
+	}
+
+	request, err := plugin.NewRequest(files, values, args...)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	response, err := generator.Run(ctx, request, os.Stderr)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	outputs, err := response.Outputs(cliOptions.OutputDir)
+	if err != nil {
+		return fmt.Errorf("%w; plugin %s", err, generator.Name)
+	}
+
+	return output.WriteFiles(outputs, cliOptions.Check)
+}
+
+// addPluginFlags adds the flags of the plugin subcommands, which select the
+// manifests and values passed to the plugin and where its files are written.
+func addPluginFlags(cmd *cobra.Command, cliOptions *options.PluginOptions) {
+	options.AddInputFlags(cmd, &cliOptions.InputOptions)
+
+	cmd.Flags().StringVar(
+		&cliOptions.OutputDir,
+		"output-dir",
+		"",
+		"directory to write the files generated by the plugin to (default the current directory)",
+	)
+
+	cmd.Flags().BoolVar(
+		&cliOptions.Check,
+		"check",
+		false,
+		"check that the files generated by the plugin are up to date, printing a diff of those which are not, without writing them",
+	)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/nukleros/gener8s/internal/command/rbac"
+	"github.com/nukleros/gener8s/internal/options"
 )
 
 // GenerateRBACCommand creates the generate subcommand.
@@ -17,9 +18,15 @@ func (r *Root) GenerateRBACCommand() *cobra.Command {
 object and get RBAC needed to manage it within a cluster (e.g. from a controller).`,
 	}
 
-	generateCmd.AddCommand(rbac.MarkersCommand(r.Options))
-	generateCmd.AddCommand(rbac.GoCommand(r.Options))
-	generateCmd.AddCommand(rbac.YAMLCommand(r.Options))
+	generateCmd.AddCommand(rbac.MarkersCommand(rbacOptions("rbac markers")))
+	generateCmd.AddCommand(rbac.GoCommand(rbacOptions("rbac go")))
+	generateCmd.AddCommand(rbac.YAMLCommand(rbacOptions("rbac yaml")))
 
 	return generateCmd
 }
+
+// rbacOptions returns the options of an rbac subcommand, with its outputs named
+// after the subcommand.
+func rbacOptions(name string) *options.RBACOptions {
+	return &options.RBACOptions{OutputOptions: options.OutputOptions{Name: name}}
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/nukleros/gener8s/internal/options"
+	"github.com/nukleros/gener8s/pkg/generate/naming"
 )
 
 // GoCommand creates the `rbac markers` subcommand.
@@ -27,6 +28,14 @@ object and get RBAC resources as unstructred Go YAML.`,
 		"name of the variable that is used for the object when generating the go code",
 	)
 
+	goCmd.Flags().StringVar(
+		&cliOptions.NamePattern,
+		"name-pattern",
+		naming.DefaultPattern,
+		"template of the names of the variables of multiple roles, from the .Kind, .Name, .Namespace, .Group "+
+			"and .Version of each role, which are prefixed with the variable name; names are made unique with a numeric suffix",
+	)
+
 	goCmd.Flags().StringVar(
 		&cliOptions.RoleName,
 		"role-name",
@@ -41,5 +50,12 @@ object and get RBAC resources as unstructred Go YAML.`,
 		"lock down rbac generation to use the 'resourceNames' field for generated rbac markers",
 	)
 
+	goCmd.Flags().StringVar(
+		&cliOptions.Package,
+		"package",
+		"",
+		"go package of the generated code; when given, complete go source files are generated",
+	)
+
 	return goCmd
 }
@@ -1,78 +1,186 @@
 package rbac
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
+	"regexp"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/nukleros/gener8s/internal/options"
+	"github.com/nukleros/gener8s/internal/output"
+	"github.com/nukleros/gener8s/internal/watch"
+	"github.com/nukleros/gener8s/pkg/generate/code"
+	"github.com/nukleros/gener8s/pkg/generate/naming"
 	"github.com/nukleros/gener8s/pkg/generate/rbac"
 	"github.com/nukleros/gener8s/pkg/manifests"
 )
 
-var ErrUnsupportedGenerateOption = errors.New("unsupported generate option")
+var (
+	ErrUnsupportedGenerateOption = errors.New("unsupported generate option")
+	ErrRolePerObject             = errors.New(
+		"the role of each object would have the same name; write a role for each manifest file with " +
+			"--group-by-source, or a single role with --output",
+	)
+)
+
+// invalidRoleNameCharacters matches the characters of the name of a manifest
+// file which are not valid in the name of a role.
+var invalidRoleNameCharacters = regexp.MustCompile(`[^a-z0-9.-]+`)
 
 // addFlags adds the common rbac flags.
-func addFlags(cmd *cobra.Command, options *options.RBACOptions) {
-	cmd.Flags().StringArrayVarP(
-		&options.ManifestFilepaths,
-		"manifest-files",
-		"m",
-		[]string{},
-		"path to manifest files containing resource definition; may include globbing",
-	)
+func addFlags(cmd *cobra.Command, cliOptions *options.RBACOptions) {
+	options.AddInputFlags(cmd, &cliOptions.InputOptions)
 
 	cmd.Flags().StringArrayVar(
-		&options.Verbs,
+		&cliOptions.Verbs,
 		"verbs",
 		rbac.DefaultResourceVerbs(),
 		"verbs needed for the rbac generation (applies to all objects passed in with the -m flag)",
 	)
 
-	cobra.CheckErr(cmd.MarkFlagRequired("manifest-files"))
+	cmd.Flags().StringVarP(
+		&cliOptions.OutputFile,
+		"output",
+		"o",
+		"",
+		"file to write the generated rbac to, rather than standard output",
+	)
+
+	cmd.Flags().StringVar(
+		&cliOptions.OutputDir,
+		"output-dir",
+		"",
+		"directory to write the generated rbac to, with a file for each manifest file with --group-by-source, which is required "+
+			"other than for markers, or otherwise for each object",
+	)
+
+	cmd.Flags().StringVar(
+		&cliOptions.OutputTemplate,
+		"filename-template",
+		"",
+		"template for the names of the files written to --output-dir "+
+			"(default \"{{ with .Namespace }}{{ . }}_{{ end }}{{ .Kind | lower }}_{{ .Name }}\", or \"{{ .Source }}\" with --group-by-source, with the extension of the output)",
+	)
+
+	cmd.Flags().BoolVar(
+		&cliOptions.GroupBySource,
+		"group-by-source",
+		false,
+		"write a file for each manifest file, rather than for each object, to --output-dir",
+	)
+
+	cmd.Flags().BoolVar(
+		&cliOptions.Check,
+		"check",
+		false,
+		"check that the output files are up to date, printing a diff of those which are not, without writing them",
+	)
+
+	cmd.Flags().BoolVar(
+		&cliOptions.Watch,
+		"watch",
+		false,
+		"regenerate the output whenever the manifests or values change, until interrupted",
+	)
 }
 
 // run adds the run function.
 func run(cliOptions *options.RBACOptions, rbacOption options.GenerateOption) func(cmd *cobra.Command, args []string) error {
 	return func(cmd *cobra.Command, args []string) error {
-		manifests, err := manifests.ExpandManifests("", cliOptions.ManifestFilepaths)
-		if err != nil {
-			return fmt.Errorf("%w", err)
+		if cliOptions.Watch {
+			return watch.Run(&watch.Target{
+				Name:   cmd.CommandPath(),
+				Inputs: cliOptions.Inputs,
+				Run:    func() error { return Generate(cliOptions, rbacOption, nil) },
+			})
 		}
 
-		// load manifest content for each manifest
-		for _, manifest := range *manifests {
-			if err = manifest.LoadContent(); err != nil {
-				return fmt.Errorf("%w", err)
-			}
+		return Generate(cliOptions, rbacOption, nil)
+	}
+}
+
+// Generate loads the manifests for a set of options and writes the rbac for
+// them, in the form of the given generate option, as specified by the output
+// options, adding the files of an output directory to the sets when given.  A
+// summary of any skipped manifests is written to standard error.
+func Generate(cliOptions *options.RBACOptions, rbacOption options.GenerateOption, sets *output.Sets) error {
+	var generate func(context.Context, *manifests.Manifests, *rbac.Options) (string, error)
+
+	extension := ".go"
+
+	switch rbacOption {
+	case options.WithYAML:
+		generate, extension = rbac.GenerateYAML, ".yaml"
+	case options.WithGo:
+		generate = rbac.GenerateCode
+	case options.WithMarkers:
+		generate = rbac.GenerateMarkers
+	default:
+		return ErrUnsupportedGenerateOption
+	}
+
+	// markers of all of the outputs are merged into a single role by
+	// controller-gen, but roles are declared by each output
+	if rbacOption != options.WithMarkers && cliOptions.OutputDir != "" && !cliOptions.GroupBySource {
+		return ErrRolePerObject
+	}
+
+	values, err := cliOptions.ReadValues()
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	files, skipped, err := cliOptions.LoadManifests()
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	if len(skipped) > 0 {
+		os.Stderr.WriteString(manifests.Summary(skipped))
+	}
+
+	// the go files written to an output directory share a package, so the
+	// roles of all of them are named by the same namer
+	namer, err := naming.New(cliOptions.NamePattern)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	return output.Write(&cliOptions.OutputOptions, sets, files, values, func(files *manifests.Manifests) (*output.Output, error) {
+		// the roles of each manifest file are named after it, so that the roles
+		// of the files of an output directory are distinct
+		roleName := cliOptions.RoleName
+		if cliOptions.OutputDir != "" {
+			roleName = sourceRoleName(roleName, (*files)[0].Filename)
 		}
 
-		var stdout string
-
-		switch rbacOption {
-		case options.WithYAML:
-			stdout, err = rbac.GenerateYAML(manifests, cliOptions)
-			if err != nil {
-				return fmt.Errorf("%w", err)
-			}
-		case options.WithGo:
-			stdout, err = rbac.GenerateCode(manifests, cliOptions)
-			if err != nil {
-				return fmt.Errorf("%w", err)
-			}
-		case options.WithMarkers:
-			stdout, err = rbac.GenerateMarkers(manifests, cliOptions)
-			if err != nil {
-				return fmt.Errorf("%w", err)
-			}
-		default:
-			return ErrUnsupportedGenerateOption
+		stdout, err := generate(context.Background(), files, &rbac.Options{
+			RoleName:         roleName,
+			Verbs:            cliOptions.Verbs,
+			UseResourceNames: cliOptions.UseResourceNames,
+			VariableName:     cliOptions.VariableName,
+			NamePattern:      cliOptions.NamePattern,
+			Namer:            namer,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%w", err)
 		}
 
-		os.Stdout.WriteString(stdout)
+		return &output.Output{Content: stdout, Code: stdout, Imports: []string{code.UnstructuredImport}}, nil
+	}, extension)
+}
 
-		return nil
+// sourceRoleName returns the name of a role for the rbac of a manifest file,
+// which is suffixed with the name of the file.
+func sourceRoleName(roleName, filename string) string {
+	suffix := strings.Trim(invalidRoleNameCharacters.ReplaceAllString(strings.ToLower(output.Source(filename)), "-"), ".-")
+	if suffix == "" {
+		return roleName
 	}
+
+	return roleName + "-" + suffix
 }
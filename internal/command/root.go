@@ -3,20 +3,17 @@
 package command
 
 import (
-	"github.com/spf13/cobra"
+	"os"
 
-	"github.com/nukleros/gener8s/internal/options"
+	"github.com/spf13/cobra"
 )
 
 type Root struct {
-	Options *options.RBACOptions
 	Command *cobra.Command
 }
 
 func New() *Root {
-	rc := &Root{
-		Options: &options.RBACOptions{},
-	}
+	rc := &Root{}
 
 	rc.Command = rc.NewCommand()
 	rc.AddCommands()
@@ -36,11 +33,19 @@ yaml manifests so that you can manage resources with Go programs.`,
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// The plugin named by the arguments, if any, is resolved here rather than by
+// discovering every plugin on the PATH.
 func (r *Root) Execute() {
+	r.AddPluginCommand(os.Args[1:])
+
 	cobra.CheckErr(r.Command.Execute())
 }
 
 func (r *Root) AddCommands() {
 	r.Command.AddCommand(r.GenerateGoCommand())
 	r.Command.AddCommand(r.GenerateRBACCommand())
+	r.Command.AddCommand(r.GenerateJobsCommand())
+	r.Command.AddCommand(r.GenCommand())
+	r.Command.AddCommand(r.GraphCommand())
+	r.Command.AddCommand(r.PluginsCommand())
 }
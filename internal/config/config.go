@@ -0,0 +1,310 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/nukleros/gener8s/internal/options"
+	"github.com/nukleros/gener8s/pkg/generate/rbac"
+	"github.com/nukleros/gener8s/pkg/manifests"
+)
+
+var (
+	ErrInvalidConfig = errors.New("invalid config file")
+	ErrUnknownJob    = errors.New("job is not defined in config file")
+)
+
+// DefaultFilename is the name of the project config file.
+const DefaultFilename = "gener8s.yaml"
+
+// defaults for the options of a job, which match the defaults of the flags of the
+// equivalent commands.
+const (
+	defaultRoleName         = "manager-role"
+	defaultVariableName     = "object"
+	defaultRBACVariableName = "resourceObj"
+)
+
+// Mode represents the type of output generated by a job.
+type Mode string
+
+const (
+	ModeGo          Mode = "go"
+	ModeRBACYAML    Mode = "rbac-yaml"
+	ModeRBACMarkers Mode = "rbac-markers"
+	ModeRBACGo      Mode = "rbac-go"
+)
+
+// Config represents the project config file, which declares the generation jobs
+// of a project.
+type Config struct {
+	Jobs []Job `yaml:"jobs"`
+
+	// dir is the directory of the config file, which the paths of the jobs are
+	// relative to.
+	dir string
+}
+
+// Job represents a single named generation job.
+type Job struct {
+	Name   string `yaml:"name"`
+	Mode   Mode   `yaml:"mode"`
+	Inputs Inputs `yaml:"inputs"`
+
+	// Values is the path to a yaml file with values to resolve templating in the
+	// manifests, and to render the chart with.
+	Values string `yaml:"values,omitempty"`
+
+	// Output is the path of the file the generated output is written to, or
+	// standard output when neither it nor the output directory is set.
+	Output string `yaml:"output,omitempty"`
+
+	// OutputDir is the directory the generated output is written to, with a file
+	// for each object, or for each manifest file when grouping by source, named
+	// from the filename template.
+	OutputDir        string `yaml:"outputDir,omitempty"`
+	FilenameTemplate string `yaml:"filenameTemplate,omitempty"`
+	GroupBySource    bool   `yaml:"groupBySource,omitempty"`
+
+	// Package is the go package of the output file, for the go and rbac-go
+	// modes.  When set, the output is written as a complete go source file.
+	Package string `yaml:"package,omitempty"`
+
+	Options JobOptions `yaml:"options,omitempty"`
+}
+
+// Inputs represents the input sources of a job, and the selection of objects
+// from them.
+type Inputs struct {
+	Manifests        []string `yaml:"manifests,omitempty"`
+	Kustomize        string   `yaml:"kustomize,omitempty"`
+	Chart            string   `yaml:"chart,omitempty"`
+	ReleaseName      string   `yaml:"releaseName,omitempty"`
+	ReleaseNamespace string   `yaml:"releaseNamespace,omitempty"`
+	Exclude          []string `yaml:"exclude,omitempty"`
+	SkipNonObjects   bool     `yaml:"skipNonObjects,omitempty"`
+	Duplicates       string   `yaml:"duplicates,omitempty"`
+	Kinds            []string `yaml:"kinds,omitempty"`
+	Names            []string `yaml:"names,omitempty"`
+	Namespaces       []string `yaml:"namespaces,omitempty"`
+	Selector         string   `yaml:"selector,omitempty"`
+}
+
+// JobOptions represents the options of a job which are specific to its mode.
+type JobOptions struct {
+	VariableName     string   `yaml:"variableName,omitempty"`
+	NamePattern      string   `yaml:"namePattern,omitempty"`
+	Collection       string   `yaml:"collection,omitempty"`
+	KindOrder        []string `yaml:"kindOrder,omitempty"`
+	Constructor      bool     `yaml:"constructor,omitempty"`
+	RoleName         string   `yaml:"roleName,omitempty"`
+	Verbs            []string `yaml:"verbs,omitempty"`
+	UseResourceNames bool     `yaml:"useResourceNames,omitempty"`
+}
+
+// Load reads and validates the config file at a path.
+func Load(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w; unable to read config file %s", err, path)
+	}
+
+	config := &Config{dir: filepath.Dir(path)}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(config); err != nil {
+		return nil, fmt.Errorf("%w; %s %s", err, ErrInvalidConfig, path)
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("%w; %s %s", err, ErrInvalidConfig, path)
+	}
+
+	return config, nil
+}
+
+// validate ensures each job is named uniquely and has a known mode.
+func (config *Config) validate() error {
+	names := map[string]bool{}
+
+	for i := range config.Jobs {
+		job := &config.Jobs[i]
+
+		switch {
+		case job.Name == "":
+			return fmt.Errorf("job %d is missing a name", i+1)
+		case names[job.Name]:
+			return fmt.Errorf("job %s is defined more than once", job.Name)
+		case job.Output != "" && job.OutputDir != "":
+			return fmt.Errorf("job %s may only set one of output and outputDir", job.Name)
+		case job.Package != "" && job.Mode != ModeGo && job.Mode != ModeRBACGo:
+			return fmt.Errorf("job %s may only set a package for the %s and %s modes", job.Name, ModeGo, ModeRBACGo)
+		case job.Options.Collection != "" && job.Mode != ModeGo:
+			return fmt.Errorf("job %s may only set a collection for the %s mode", job.Name, ModeGo)
+		case job.OutputDir != "" && !job.GroupBySource && (job.Mode == ModeRBACYAML || job.Mode == ModeRBACGo):
+			return fmt.Errorf("job %s must set groupBySource to write the %s mode to an outputDir, "+
+				"as the role of each object would have the same name", job.Name, job.Mode)
+		}
+
+		switch job.Mode {
+		case ModeGo, ModeRBACYAML, ModeRBACMarkers, ModeRBACGo:
+		default:
+			return fmt.Errorf("job %s has unknown mode %q", job.Name, job.Mode)
+		}
+
+		names[job.Name] = true
+	}
+
+	return nil
+}
+
+// Select returns the jobs with the given names, in the order they are given, or
+// all jobs when no names are given.
+func (config *Config) Select(names ...string) ([]*Job, error) {
+	var jobs []*Job
+
+	if len(names) == 0 {
+		for i := range config.Jobs {
+			jobs = append(jobs, &config.Jobs[i])
+		}
+
+		return jobs, nil
+	}
+
+	for _, name := range names {
+		job := config.job(name)
+		if job == nil {
+			return nil, fmt.Errorf("%w; %s", ErrUnknownJob, name)
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+// job returns the job with a name, or nil if there is none.
+func (config *Config) job(name string) *Job {
+	for i := range config.Jobs {
+		if config.Jobs[i].Name == name {
+			return &config.Jobs[i]
+		}
+	}
+
+	return nil
+}
+
+// Path returns a path of a job relative to the directory of the config file.
+func (config *Config) Path(path string) string {
+	if path == "" || path == options.Stdin || filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(config.dir, path)
+}
+
+// InputOptions returns the options which select the inputs of a job.  Paths of
+// the job, along with its exclude patterns and the ignore file, are relative to
+// the directory of the config file.
+func (config *Config) InputOptions(job *Job) options.InputOptions {
+	inputOptions := options.InputOptions{
+		KustomizeDir:     config.Path(job.Inputs.Kustomize),
+		ChartPath:        config.Path(job.Inputs.Chart),
+		ReleaseName:      job.Inputs.ReleaseName,
+		ReleaseNamespace: job.Inputs.ReleaseNamespace,
+		Excludes:         job.Inputs.Exclude,
+		BaseDir:          config.Path("."),
+		SkipNonObjects:   job.Inputs.SkipNonObjects,
+		Duplicates:       job.Inputs.Duplicates,
+		ValuesFilePath:   config.Path(job.Values),
+		Filter: manifests.Filter{
+			Kinds:      job.Inputs.Kinds,
+			Names:      job.Inputs.Names,
+			Namespaces: job.Inputs.Namespaces,
+			Selector:   job.Inputs.Selector,
+		},
+	}
+
+	for _, path := range job.Inputs.Manifests {
+		inputOptions.ManifestFilepaths = append(inputOptions.ManifestFilepaths, config.Path(path))
+	}
+
+	if inputOptions.Duplicates == "" {
+		inputOptions.Duplicates = string(manifests.DuplicatesError)
+	}
+
+	return inputOptions
+}
+
+// outputOptions returns the options which select the output of a job, with
+// paths relative to the directory of the config file.
+func (config *Config) outputOptions(job *Job) options.OutputOptions {
+	return options.OutputOptions{
+		Name:           job.Name,
+		OutputFile:     config.Path(job.Output),
+		OutputDir:      config.Path(job.OutputDir),
+		OutputTemplate: job.FilenameTemplate,
+		GroupBySource:  job.GroupBySource,
+		Package:        job.Package,
+	}
+}
+
+// GoOptions returns the options which a job of the go mode is run with.
+// Options which are not set default to the defaults of the equivalent command
+// line flags.
+func (config *Config) GoOptions(job *Job) *options.GoOptions {
+	jobOptions := &options.GoOptions{
+		InputOptions:  config.InputOptions(job),
+		OutputOptions: config.outputOptions(job),
+		VariableName:  job.Options.VariableName,
+		NamePattern:   job.Options.NamePattern,
+		Collection:    job.Options.Collection,
+		KindOrder:     job.Options.KindOrder,
+		Constructor:   job.Options.Constructor,
+	}
+
+	if jobOptions.VariableName == "" {
+		jobOptions.VariableName = defaultVariableName
+	}
+
+	return jobOptions
+}
+
+// RBACOptions returns the options which a job of one of the rbac modes is run
+// with.  Options which are not set default to the defaults of the equivalent
+// command line flags.
+func (config *Config) RBACOptions(job *Job) *options.RBACOptions {
+	jobOptions := &options.RBACOptions{
+		InputOptions:     config.InputOptions(job),
+		OutputOptions:    config.outputOptions(job),
+		VariableName:     job.Options.VariableName,
+		NamePattern:      job.Options.NamePattern,
+		RoleName:         job.Options.RoleName,
+		Verbs:            job.Options.Verbs,
+		UseResourceNames: job.Options.UseResourceNames,
+	}
+
+	if jobOptions.RoleName == "" {
+		jobOptions.RoleName = defaultRoleName
+	}
+
+	if len(jobOptions.Verbs) == 0 {
+		jobOptions.Verbs = rbac.DefaultResourceVerbs()
+	}
+
+	if jobOptions.VariableName == "" {
+		jobOptions.VariableName = defaultRBACVariableName
+	}
+
+	return jobOptions
+}
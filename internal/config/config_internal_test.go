@@ -0,0 +1,266 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nukleros/gener8s/internal/options"
+	"github.com/nukleros/gener8s/pkg/generate/rbac"
+	"github.com/nukleros/gener8s/pkg/manifests"
+)
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		want    []Job
+		wantErr bool
+	}{
+		{
+			name: "ensure jobs are loaded",
+			content: `jobs:
+  - name: webstore
+    mode: go
+    inputs:
+      manifests:
+        - config/*.yaml
+      kinds:
+        - Deployment
+    output: pkg/webstore/zz_generated.go
+    package: webstore
+    options:
+      constructor: true
+`,
+			want: []Job{
+				{
+					Name:    "webstore",
+					Mode:    ModeGo,
+					Inputs:  Inputs{Manifests: []string{"config/*.yaml"}, Kinds: []string{"Deployment"}},
+					Output:  "pkg/webstore/zz_generated.go",
+					Package: "webstore",
+					Options: JobOptions{Constructor: true},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "ensure unknown fields are rejected",
+			content: "jobs:\n  - name: webstore\n    mode: go\n    unknown: true\n",
+			wantErr: true,
+		},
+		{
+			name:    "ensure jobs without a name are rejected",
+			content: "jobs:\n  - mode: go\n",
+			wantErr: true,
+		},
+		{
+			name:    "ensure duplicate job names are rejected",
+			content: "jobs:\n  - name: webstore\n    mode: go\n  - name: webstore\n    mode: rbac-yaml\n",
+			wantErr: true,
+		},
+		{
+			name:    "ensure unknown modes are rejected",
+			content: "jobs:\n  - name: webstore\n    mode: python\n",
+			wantErr: true,
+		},
+		{
+			name:    "ensure jobs with both an output file and directory are rejected",
+			content: "jobs:\n  - name: webstore\n    mode: go\n    output: web.go\n    outputDir: web\n",
+			wantErr: true,
+		},
+		{
+			name:    "ensure packages are rejected for modes which do not generate go",
+			content: "jobs:\n  - name: rbac\n    mode: rbac-yaml\n    package: rbac\n",
+			wantErr: true,
+		},
+		{
+			name:    "ensure collections are rejected for modes other than go",
+			content: "jobs:\n  - name: rbac\n    mode: rbac-go\n    options:\n      collection: Objects\n",
+			wantErr: true,
+		},
+		{
+			name:    "ensure roles are not split by object for modes which name them",
+			content: "jobs:\n  - name: rbac\n    mode: rbac-yaml\n    outputDir: config/rbac\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), DefaultFilename)
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := Load(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Load() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if !tt.wantErr {
+				assert.Equal(t, tt.want, got.Jobs)
+			}
+		})
+	}
+}
+
+func TestConfig_Select(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{Jobs: []Job{{Name: "webstore"}, {Name: "rbac"}}}
+
+	tests := []struct {
+		name    string
+		names   []string
+		want    []string
+		wantErr error
+	}{
+		{
+			name:  "ensure all jobs are selected when no names are given",
+			names: nil,
+			want:  []string{"webstore", "rbac"},
+		},
+		{
+			name:  "ensure named jobs are selected in the given order",
+			names: []string{"rbac", "webstore"},
+			want:  []string{"rbac", "webstore"},
+		},
+		{
+			name:    "ensure unknown jobs are rejected",
+			names:   []string{"missing"},
+			wantErr: ErrUnknownJob,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			jobs, err := config.Select(tt.names...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Select() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var got []string
+			for _, job := range jobs {
+				got = append(got, job.Name)
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConfig_GoOptions(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{dir: "project"}
+
+	job := &Job{
+		Mode: ModeGo,
+		Inputs: Inputs{
+			Manifests: []string{"config/*.yaml", options.Stdin, "/abs/web.yaml"},
+			Chart:     "charts/web",
+			Exclude:   []string{"testdata/"},
+			Kinds:     []string{"Deployment"},
+		},
+		Values:    "values.yaml",
+		OutputDir: "pkg/web",
+		Package:   "web",
+		Options:   JobOptions{Constructor: true},
+	}
+
+	assert.Equal(t, &options.GoOptions{
+		InputOptions: options.InputOptions{
+			ManifestFilepaths: []string{"project/config/*.yaml", options.Stdin, "/abs/web.yaml"},
+			ChartPath:         "project/charts/web",
+			ValuesFilePath:    "project/values.yaml",
+			Excludes:          []string{"testdata/"},
+			BaseDir:           "project",
+			Duplicates:        string(manifests.DuplicatesError),
+			Filter:            manifests.Filter{Kinds: []string{"Deployment"}},
+		},
+		OutputOptions: options.OutputOptions{
+			OutputDir: "project/pkg/web",
+			Package:   "web",
+		},
+		VariableName: defaultVariableName,
+		Constructor:  true,
+	}, config.GoOptions(job))
+}
+
+func TestConfig_RBACOptions(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{dir: "project"}
+
+	tests := []struct {
+		name string
+		job  *Job
+		want *options.RBACOptions
+	}{
+		{
+			name: "ensure paths are relative to the config file and defaults are applied",
+			job: &Job{
+				Mode: ModeRBACYAML,
+				Inputs: Inputs{
+					Manifests: []string{"config/*.yaml"},
+					Kinds:     []string{"Deployment"},
+				},
+				Output: "config/rbac/role.yaml",
+			},
+			want: &options.RBACOptions{
+				InputOptions: options.InputOptions{
+					ManifestFilepaths: []string{"project/config/*.yaml"},
+					BaseDir:           "project",
+					Duplicates:        string(manifests.DuplicatesError),
+					Filter:            manifests.Filter{Kinds: []string{"Deployment"}},
+				},
+				OutputOptions: options.OutputOptions{OutputFile: "project/config/rbac/role.yaml"},
+				VariableName:  defaultRBACVariableName,
+				RoleName:      defaultRoleName,
+				Verbs:         rbac.DefaultResourceVerbs(),
+			},
+		},
+		{
+			name: "ensure the options of the job are used",
+			job: &Job{
+				Mode:    ModeRBACGo,
+				Inputs:  Inputs{Duplicates: string(manifests.DuplicatesKeepLast)},
+				Options: JobOptions{RoleName: "web-role", Verbs: []string{"get"}},
+			},
+			want: &options.RBACOptions{
+				InputOptions: options.InputOptions{
+					BaseDir:    "project",
+					Duplicates: string(manifests.DuplicatesKeepLast),
+				},
+				VariableName: defaultRBACVariableName,
+				RoleName:     "web-role",
+				Verbs:        []string{"get"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, config.RBACOptions(tt.job))
+		})
+	}
+}
@@ -0,0 +1,304 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package directive
+
+import (
+	"errors"
+	"fmt"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/nukleros/gener8s/internal/options"
+	"github.com/nukleros/gener8s/pkg/manifests"
+)
+
+var (
+	ErrInvalidDirective = errors.New("invalid gener8s directive")
+	ErrMultiplePackages = errors.New("multiple packages in directory")
+)
+
+const (
+	// Prefix is the prefix of the comments which are directives to generate
+	// objects into the package of the go source file.
+	Prefix = "//gener8s:object"
+
+	// GeneratedFilename is the name of the go source file, in the same directory
+	// as the directives, which the objects are generated into.
+	GeneratedFilename = "zz_generated.gener8s.go"
+)
+
+// recursive is the suffix of a package pattern which matches the directory and
+// all of the directories below it.
+const recursive = "/..."
+
+// Directive represents a single directive to generate objects from manifests,
+// in the form:
+//
+//	//gener8s:object manifests/deploy.yaml [more manifests...] [key=value...]
+//
+// where the manifest paths are relative to the directory of the go source file,
+// and may include globbing.  The supported keys are var, constructor, values,
+// kind, name, namespace and selector.
+type Directive struct {
+	// Filename and Line are the position of the directive.
+	Filename string
+	Line     int
+
+	// Manifests are the paths of the manifest files to generate objects from.
+	Manifests []string
+
+	// Var names the variable, or the constructor, of a single object, and
+	// prefixes the names of multiple objects.
+	Var string
+
+	// Constructor determines if constructor functions are generated rather than
+	// variables.
+	Constructor bool
+
+	// Values is the path of a yaml file with values to resolve templating in the
+	// manifests.
+	Values string
+
+	// Filter selects the objects of the manifests to generate.
+	Filter manifests.Filter
+}
+
+// Package represents a go package with directives.
+type Package struct {
+	// Dir is the directory of the package.
+	Dir string
+
+	// Name is the name of the package.
+	Name string
+
+	// Directives are the directives of the package, in order of their file and
+	// position.
+	Directives []*Directive
+}
+
+// Dirs returns the directories matched by package patterns, in the form used by
+// the go tool, where a pattern ending in /... matches the directory and all of
+// the directories below it.  Directories which the go tool ignores, such as
+// testdata, vendor and those starting with . or _, are skipped when matching
+// below a directory.
+func Dirs(patterns ...string) ([]string, error) {
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+
+	var dirs []string
+
+	seen := map[string]bool{}
+
+	add := func(dir string) {
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+
+	for _, pattern := range patterns {
+		if pattern != recursive[1:] && !strings.HasSuffix(pattern, recursive) {
+			add(filepath.Clean(pattern))
+
+			continue
+		}
+
+		root := strings.TrimSuffix(strings.TrimSuffix(pattern, recursive[1:]), "/")
+		if root == "" {
+			root = "."
+		}
+
+		if err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if !entry.IsDir() {
+				return nil
+			}
+
+			if path != root && ignoredDir(entry.Name()) {
+				return filepath.SkipDir
+			}
+
+			add(path)
+
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("%w; unable to expand package pattern %s", err, pattern)
+		}
+	}
+
+	return dirs, nil
+}
+
+// ignoredDir determines if a directory is ignored by the go tool when matching
+// packages below a directory.
+func ignoredDir(name string) bool {
+	return name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+}
+
+// Parse parses the directives of the go package in a directory, in order of
+// their file and position.  Test files, the generated file and files which are
+// excluded by build constraints, as with go build, are not parsed.  A nil package
+// is returned when the directory has no go source files.
+func Parse(dir string) (*Package, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("%w; unable to read package directory %s", err, dir)
+	}
+
+	var pkg *Package
+
+	fileSet := token.NewFileSet()
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") || name == GeneratedFilename {
+			continue
+		}
+
+		filename := filepath.Join(dir, name)
+
+		// files excluded by build constraints, such as a package main helper
+		// with a go:build ignore constraint, are not part of the package
+		match, err := build.Default.MatchFile(dir, name)
+		if err != nil {
+			return nil, fmt.Errorf("%w; unable to read build constraints of go source file %s", err, filename)
+		}
+
+		if !match {
+			continue
+		}
+
+		file, err := parser.ParseFile(fileSet, filename, nil, parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("%w; unable to parse go source file %s", err, filename)
+		}
+
+		if pkg == nil {
+			pkg = &Package{Dir: dir, Name: file.Name.Name}
+		} else if pkg.Name != file.Name.Name {
+			return nil, fmt.Errorf("%w; %s has packages %s and %s", ErrMultiplePackages, dir, pkg.Name, file.Name.Name)
+		}
+
+		for _, group := range file.Comments {
+			for _, comment := range group.List {
+				if comment.Text != Prefix && !strings.HasPrefix(comment.Text, Prefix+" ") {
+					continue
+				}
+
+				position := fileSet.Position(comment.Pos())
+
+				directive, err := parseDirective(strings.TrimPrefix(comment.Text, Prefix))
+				if err != nil {
+					return nil, &manifests.PositionError{
+						Filename: filename,
+						Line:     position.Line,
+						Column:   position.Column,
+						Err:      err,
+					}
+				}
+
+				directive.Filename, directive.Line = filename, position.Line
+				pkg.Directives = append(pkg.Directives, directive)
+			}
+		}
+	}
+
+	return pkg, nil
+}
+
+// parseDirective parses the arguments of a directive.
+func parseDirective(arguments string) (*Directive, error) {
+	directive := &Directive{}
+
+	for _, argument := range strings.Fields(arguments) {
+		option := strings.SplitN(argument, "=", 2)
+		if len(option) == 1 {
+			if argument == "constructor" {
+				directive.Constructor = true
+			} else {
+				directive.Manifests = append(directive.Manifests, argument)
+			}
+
+			continue
+		}
+
+		key, value := option[0], option[1]
+
+		switch key {
+		case "var":
+			directive.Var = value
+		case "constructor":
+			constructor, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w; constructor must be true or false, got %q", ErrInvalidDirective, value)
+			}
+
+			directive.Constructor = constructor
+		case "values":
+			directive.Values = value
+		case "kind":
+			directive.Filter.Kinds = append(directive.Filter.Kinds, value)
+		case "name":
+			directive.Filter.Names = append(directive.Filter.Names, value)
+		case "namespace":
+			directive.Filter.Namespaces = append(directive.Filter.Namespaces, value)
+		case "selector":
+			directive.Filter.Selector = value
+		default:
+			return nil, fmt.Errorf("%w; unknown key %q", ErrInvalidDirective, key)
+		}
+	}
+
+	if len(directive.Manifests) == 0 {
+		return nil, fmt.Errorf("%w; at least one manifest file must be given", ErrInvalidDirective)
+	}
+
+	for _, path := range directive.Manifests {
+		if path == options.Stdin {
+			return nil, fmt.Errorf("%w; manifests may not be read from standard input", ErrInvalidDirective)
+		}
+	}
+
+	return directive, nil
+}
+
+// Options returns the options which the objects of the directive are generated
+// with.  Paths are relative to the directory of the go source file.
+func (directive *Directive) Options() *options.GoOptions {
+	dir := filepath.Dir(directive.Filename)
+
+	directiveOptions := &options.GoOptions{
+		InputOptions: options.InputOptions{Filter: directive.Filter},
+		Constructor:  directive.Constructor,
+	}
+
+	for _, path := range directive.Manifests {
+		directiveOptions.ManifestFilepaths = append(directiveOptions.ManifestFilepaths, relative(dir, path))
+	}
+
+	if directive.Values != "" {
+		directiveOptions.ValuesFilePath = relative(dir, directive.Values)
+	}
+
+	return directiveOptions
+}
+
+// relative returns a path relative to a directory, unless it is absolute.
+func relative(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(dir, path)
+}
@@ -0,0 +1,182 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package directive
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nukleros/gener8s/pkg/manifests"
+)
+
+func Test_parseDirective(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		arguments string
+		want      *Directive
+		wantErr   error
+	}{
+		{
+			name:      "ensure manifests and options are parsed",
+			arguments: " manifests/deploy.yaml manifests/*.yaml var=deployment constructor values=values.yaml",
+			want: &Directive{
+				Manifests:   []string{"manifests/deploy.yaml", "manifests/*.yaml"},
+				Var:         "deployment",
+				Constructor: true,
+				Values:      "values.yaml",
+			},
+		},
+		{
+			name:      "ensure filters are parsed",
+			arguments: " manifests/ kind=Deployment kind=Service name=web namespace=shop selector=tier=frontend",
+			want: &Directive{
+				Manifests: []string{"manifests/"},
+				Filter: manifests.Filter{
+					Kinds:      []string{"Deployment", "Service"},
+					Names:      []string{"web"},
+					Namespaces: []string{"shop"},
+					Selector:   "tier=frontend",
+				},
+			},
+		},
+		{
+			name:      "ensure a constructor value is parsed",
+			arguments: " deploy.yaml constructor=false",
+			want:      &Directive{Manifests: []string{"deploy.yaml"}},
+		},
+		{
+			name:      "ensure unknown keys are rejected",
+			arguments: " deploy.yaml bogus=1",
+			wantErr:   ErrInvalidDirective,
+		},
+		{
+			name:      "ensure directives without manifests are rejected",
+			arguments: " var=deployment",
+			wantErr:   ErrInvalidDirective,
+		},
+		{
+			name:      "ensure standard input is rejected",
+			arguments: " -",
+			wantErr:   ErrInvalidDirective,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseDirective(tt.arguments)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("parseDirective() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	for name, content := range map[string]string{
+		"a.go":            "package web\n\n//gener8s:object deploy.yaml var=deployment\n",
+		"b.go":            "package web\n\n// gener8s:object ignored.yaml\n\n//gener8s:object svc.yaml\n",
+		"b_test.go":       "package web_test\n\n//gener8s:object test.yaml\n",
+		"gen.go":          "//go:build ignore\n\npackage main\n\n//gener8s:object ignored.yaml\n",
+		GeneratedFilename: "package web\n\n//gener8s:object generated.yaml\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := Parse(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, &Package{
+		Dir:  dir,
+		Name: "web",
+		Directives: []*Directive{
+			{Filename: filepath.Join(dir, "a.go"), Line: 3, Manifests: []string{"deploy.yaml"}, Var: "deployment"},
+			{Filename: filepath.Join(dir, "b.go"), Line: 5, Manifests: []string{"svc.yaml"}},
+		},
+	}, got)
+
+	assert.Equal(t, []string{filepath.Join(dir, "deploy.yaml")}, got.Directives[0].Options().ManifestFilepaths)
+}
+
+func TestParse_errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name:  "ensure invalid directives are reported at their position",
+			files: map[string]string{"a.go": "package web\n\n//gener8s:object deploy.yaml bogus=1\n"},
+			want:  "{dir}/a.go:3:1: invalid gener8s directive; unknown key \"bogus\"",
+		},
+		{
+			name:  "ensure multiple packages are rejected",
+			files: map[string]string{"a.go": "package web\n", "b.go": "package db\n"},
+			want:  "multiple packages in directory; {dir} has packages web and db",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, err := Parse(dir)
+			assert.EqualError(t, err, strings.ReplaceAll(tt.want, "{dir}", dir))
+		})
+	}
+}
+
+func TestDirs(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+
+	for _, dir := range []string{"pkg/web", "pkg/web/testdata", "pkg/.hidden", "pkg/_skip", "vendor/dep"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := Dirs(root+"/...", filepath.Join(root, "pkg"), filepath.Join(root, "vendor", "dep"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []string{
+		root,
+		filepath.Join(root, "pkg"),
+		filepath.Join(root, "pkg", "web"),
+		filepath.Join(root, "vendor", "dep"),
+	}, got)
+}
@@ -0,0 +1,109 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package options
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/nukleros/gener8s/pkg/manifests"
+)
+
+// AddInputFlags adds the flags which select the manifests, and the objects
+// within them, to a command.
+func AddInputFlags(cmd *cobra.Command, options *InputOptions) {
+	cmd.Flags().StringArrayVarP(
+		&options.ManifestFilepaths,
+		"manifest-files",
+		"m",
+		[]string{},
+		"path to manifest files containing resource definition; may include globbing, or - to read from standard input",
+	)
+
+	cmd.Flags().StringArrayVar(
+		&options.Excludes,
+		"exclude",
+		[]string{},
+		"glob pattern of manifest files to exclude, with the same semantics as a .gener8signore file",
+	)
+
+	cmd.Flags().BoolVar(
+		&options.SkipNonObjects,
+		"skip-non-objects",
+		false,
+		"skip yaml documents which have no apiVersion or kind rather than failing to decode them",
+	)
+
+	cmd.Flags().StringVar(
+		&options.Duplicates,
+		"duplicates",
+		string(manifests.DuplicatesError),
+		"how objects defined more than once are resolved, either error, keep-first, keep-last or merge",
+	)
+
+	cmd.Flags().StringArrayVar(
+		&options.Filter.Kinds,
+		"kind",
+		[]string{},
+		"only select objects of this kind; may be given multiple times",
+	)
+
+	cmd.Flags().StringArrayVar(
+		&options.Filter.Names,
+		"name",
+		[]string{},
+		"only select objects with this name; may be given multiple times",
+	)
+
+	cmd.Flags().StringArrayVar(
+		&options.Filter.Namespaces,
+		"namespace",
+		[]string{},
+		"only select objects in this namespace; may be given multiple times",
+	)
+
+	cmd.Flags().StringVarP(
+		&options.Filter.Selector,
+		"selector",
+		"l",
+		"",
+		"only select objects matching this label selector (e.g. -l 'app=web,tier in (frontend,backend)')",
+	)
+
+	cmd.Flags().StringVar(
+		&options.KustomizeDir,
+		"kustomize",
+		"",
+		"path to a directory containing a kustomization to build the resource definitions from",
+	)
+
+	cmd.Flags().StringVar(
+		&options.ChartPath,
+		"chart",
+		"",
+		"path to a local helm chart to render the resource definitions from",
+	)
+
+	cmd.Flags().StringVar(
+		&options.ReleaseName,
+		"release-name",
+		manifests.DefaultReleaseName,
+		"name of the release when rendering the chart",
+	)
+
+	cmd.Flags().StringVar(
+		&options.ReleaseNamespace,
+		"release-namespace",
+		manifests.DefaultReleaseNamespace,
+		"namespace of the release when rendering the chart",
+	)
+
+	cmd.Flags().StringVarP(
+		&options.ValuesFilePath,
+		"values-file",
+		"f",
+		"",
+		"yaml file with values to resolve the templating of the manifests with, and to render the chart with "+
+			"when --chart is given",
+	)
+}
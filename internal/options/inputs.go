@@ -0,0 +1,118 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package options
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/nukleros/gener8s/pkg/manifests"
+)
+
+var ErrWatchStdin = errors.New("manifests may not be read from standard input when watching")
+
+// globMeta are the characters which make a path a glob pattern.
+const globMeta = `*?[\`
+
+// Inputs returns the files which the output of the options is generated from,
+// being the manifest files, the files of the kustomization and chart, the values
+// file and the ignore file, along with the directories in which files may be
+// added or removed to change them, such as those matched by glob patterns.
+// Manifest patterns which match no files are not an error, so that the
+// directories are still returned.
+func (options *InputOptions) Inputs() (files, dirs []string, err error) {
+	ignore, err := options.ignore()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	files = append(files, options.ignoreFile())
+	dirs = append(dirs, options.baseDir())
+
+	for _, pattern := range options.ManifestFilepaths {
+		if pattern == Stdin {
+			return nil, nil, ErrWatchStdin
+		}
+
+		dirs = append(dirs, patternDirs(pattern)...)
+
+		// patterns which match no files are reported when generating
+		expanded, expandErr := manifests.ExpandManifests("", []string{pattern})
+		if expandErr != nil {
+			continue
+		}
+
+		expanded, _ = expanded.Exclude(ignore, options.baseDir())
+
+		for _, manifest := range *expanded {
+			files = append(files, manifest.Filename)
+		}
+	}
+
+	for _, dir := range []string{options.KustomizeDir, options.ChartPath} {
+		if dir == "" {
+			continue
+		}
+
+		treeFiles, treeDirs := tree(dir)
+		files = append(files, treeFiles...)
+		dirs = append(dirs, treeDirs...)
+	}
+
+	if options.ValuesFilePath != "" {
+		files = append(files, options.ValuesFilePath)
+	}
+
+	for _, file := range files {
+		dirs = append(dirs, filepath.Dir(file))
+	}
+
+	return files, dirs, nil
+}
+
+// patternDirs returns the directories in which files may be added or removed to
+// change the files matched by a manifest pattern.  These are the directories
+// matched by the directory of the pattern, along with the directory before its
+// first glob, and all of the directories below that for double-star patterns.
+func patternDirs(pattern string) []string {
+	root := pattern
+	for strings.ContainsAny(root, globMeta) {
+		root = filepath.Dir(root)
+	}
+
+	if strings.Contains(pattern, "**") {
+		_, dirs := tree(root)
+
+		return dirs
+	}
+
+	dirs := []string{root}
+
+	if matches, err := filepath.Glob(filepath.Dir(pattern)); err == nil {
+		dirs = append(dirs, matches...)
+	}
+
+	return dirs
+}
+
+// tree returns the files and directories of a directory tree, including the
+// directory itself.  Unreadable parts of the tree are skipped.
+func tree(root string) (files, dirs []string) {
+	_ = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		switch {
+		case err != nil:
+			return nil
+		case entry.IsDir():
+			dirs = append(dirs, path)
+		default:
+			files = append(files, path)
+		}
+
+		return nil
+	})
+
+	return files, dirs
+}
@@ -0,0 +1,179 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package options
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/nukleros/gener8s/pkg/manifests"
+)
+
+var (
+	ErrMissingManifests = errors.New("at least one of --manifest-files, --kustomize or --chart must be specified")
+	ErrMultipleStdin    = errors.New("standard input may only be given once with --manifest-files -")
+)
+
+// Stdin is the manifest file path which reads the manifests from standard input.
+const Stdin = "-"
+
+// ReadValues reads the values from the values file specified by the options, if
+// any.
+func (options *InputOptions) ReadValues() (map[string]interface{}, error) {
+	var values map[string]interface{}
+
+	if options.ValuesFilePath == "" {
+		return values, nil
+	}
+
+	valuesFile, err := os.ReadFile(options.ValuesFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	if err := yaml.Unmarshal(valuesFile, &values); err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return values, nil
+}
+
+// LoadManifests expands and loads the manifests from all of the input sources
+// specified by the options, along with the manifest files and documents which
+// were skipped.  Manifest files matched by the exclude patterns, or by the
+// patterns in the ignore file of the base directory, are skipped.  Objects
+// which are defined more than once are resolved by the duplicate policy of the
+// options before the objects selected by the filter of the options are
+// returned.  The filter matches the objects as they are once their templating
+// is resolved by the values of the options.
+func (options *InputOptions) LoadManifests() (*manifests.Manifests, []manifests.Skipped, error) {
+	if len(options.ManifestFilepaths) == 0 && options.KustomizeDir == "" && options.ChartPath == "" {
+		return nil, nil, ErrMissingManifests
+	}
+
+	ignore, err := options.ignore()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	values, err := options.ReadValues()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	loaded := manifests.Manifests{}
+
+	var skipped []manifests.Skipped
+
+	var stdin bool
+
+	for _, path := range options.ManifestFilepaths {
+		if path != Stdin {
+			files, err := manifests.ExpandManifests("", []string{path})
+			if err != nil {
+				return nil, nil, fmt.Errorf("%w", err)
+			}
+
+			files, excluded := files.Exclude(ignore, options.baseDir())
+			skipped = append(skipped, excluded...)
+
+			// load manifest content for each manifest
+			for _, manifest := range *files {
+				if err = manifest.LoadContent(); err != nil {
+					return nil, nil, fmt.Errorf("%w", err)
+				}
+			}
+
+			loaded = append(loaded, *files...)
+
+			continue
+		}
+
+		if stdin {
+			return nil, nil, ErrMultipleStdin
+		}
+
+		stdin = true
+
+		manifest, err := manifests.FromReader(os.Stdin, manifests.StdinFilename)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w", err)
+		}
+
+		loaded = append(loaded, manifest)
+	}
+
+	if options.KustomizeDir != "" {
+		rendered, err := manifests.FromKustomization(options.KustomizeDir)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w", err)
+		}
+
+		loaded = append(loaded, *rendered...)
+	}
+
+	if options.ChartPath != "" {
+		rendered, err := manifests.FromChart(options.ChartPath, options.ReleaseName, options.ReleaseNamespace, values)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w", err)
+		}
+
+		loaded = append(loaded, *rendered...)
+	}
+
+	objects := &loaded
+
+	if options.SkipNonObjects {
+		var nonObjects []manifests.Skipped
+
+		objects, nonObjects = objects.SkipNonObjects()
+		skipped = append(skipped, nonObjects...)
+	}
+
+	objects, duplicates, err := objects.Deduplicate(manifests.DuplicatePolicy(options.Duplicates))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w", err)
+	}
+
+	skipped = append(skipped, duplicates...)
+
+	selected, err := objects.Filter(&options.Filter, values)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w", err)
+	}
+
+	return selected, skipped, nil
+}
+
+// baseDir returns the directory which the exclude patterns, and the ignore
+// file, are relative to.
+func (options *InputOptions) baseDir() string {
+	if options.BaseDir == "" {
+		return "."
+	}
+
+	return options.BaseDir
+}
+
+// ignoreFile returns the path of the ignore file of the base directory.
+func (options *InputOptions) ignoreFile() string {
+	return filepath.Join(options.baseDir(), manifests.IgnoreFilename)
+}
+
+// ignore returns the patterns of the manifest files which are skipped, from the
+// ignore file of the base directory and the exclude patterns.
+func (options *InputOptions) ignore() (*manifests.Ignore, error) {
+	ignore, err := manifests.ReadIgnoreFile(options.ignoreFile())
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	ignore.Add(options.Excludes...)
+
+	return ignore, nil
+}
@@ -1,5 +1,7 @@
 package options
 
+import "github.com/nukleros/gener8s/pkg/manifests"
+
 type GenerateOption int
 
 const (
@@ -8,12 +10,94 @@ const (
 	WithGo
 )
 
-type RBACOptions struct {
+// InputOptions represents the options which select the manifests, and the
+// values, which a command generates its output from.
+type InputOptions struct {
 	ManifestFilepaths []string
-	ManifestFilepath  string
-	RoleName          string
-	VariableName      string
 	ValuesFilePath    string
-	Verbs             []string
-	UseResourceNames  bool
+	KustomizeDir      string
+	ChartPath         string
+	ReleaseName       string
+	ReleaseNamespace  string
+	Excludes          []string
+	SkipNonObjects    bool
+	Duplicates        string
+	Filter            manifests.Filter
+
+	// BaseDir is the directory which the exclude patterns, and the ignore file,
+	// are relative to, being the current directory when empty.
+	BaseDir string
+}
+
+// OutputOptions represents the options which select where, and in which form,
+// a command writes its generated output.
+type OutputOptions struct {
+	// Name identifies the set of outputs written to the output directory, so
+	// that several sets may share the directory.
+	Name string
+
+	OutputFile     string
+	OutputDir      string
+	OutputTemplate string
+	GroupBySource  bool
+	Package        string
+	Check          bool
+	Watch          bool
+}
+
+// RBACOptions represents the options of the rbac commands.
+type RBACOptions struct {
+	InputOptions
+	OutputOptions
+
+	ManifestFilepath string
+	RoleName         string
+	VariableName     string
+	NamePattern      string
+	Verbs            []string
+	UseResourceNames bool
+}
+
+// GoOptions represents the options of the go command.
+type GoOptions struct {
+	InputOptions
+	OutputOptions
+
+	VariableName string
+	NamePattern  string
+	Collection   string
+	KindOrder    []string
+	Constructor  bool
+}
+
+// GraphOptions represents the options of the graph command.
+type GraphOptions struct {
+	InputOptions
+
+	OutputFile     string
+	Format         string
+	FailOnDangling bool
+}
+
+// PluginOptions represents the options of the plugin subcommands.
+type PluginOptions struct {
+	InputOptions
+
+	OutputDir string
+	Check     bool
+}
+
+// ConfigOptions represents the options of the generate command, which runs the
+// jobs of the project config file.
+type ConfigOptions struct {
+	ConfigFilepath string
+	Check          bool
+	Watch          bool
+}
+
+// DirectiveOptions represents the options of the gen command, which generates
+// objects from the directives of go packages.
+type DirectiveOptions struct {
+	NamePattern string
+	Check       bool
 }
@@ -0,0 +1,636 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package output
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/pmezard/go-difflib/difflib"
+	"gopkg.in/yaml.v3"
+
+	"github.com/nukleros/gener8s/internal/options"
+	"github.com/nukleros/gener8s/pkg/generate/code"
+	"github.com/nukleros/gener8s/pkg/manifests"
+)
+
+var (
+	ErrOutputConflict  = errors.New("only one of --output and --output-dir may be specified")
+	ErrInvalidFilename = errors.New("invalid output filename")
+	ErrDuplicateOutput = errors.New("multiple outputs are written to the same file")
+	ErrCheckStdout     = errors.New("--check requires --output or --output-dir")
+	ErrOutOfDate       = errors.New("generated output is out of date; regenerate the output files")
+)
+
+// generatedHeader is the header of the go source files written with a package,
+// which marks them as generated.
+const generatedHeader = "// Code generated by gener8s. DO NOT EDIT."
+
+// default filename templates, without an extension, for outputs of a single
+// object and of a single source manifest file.  Objects are named after their
+// namespace, when they have one, so that objects of the same kind and name in
+// different namespaces are written to different files.
+const (
+	defaultObjectTemplate = "{{ with .Namespace }}{{ . }}_{{ end }}{{ .Kind | lower }}_{{ .Name }}"
+	defaultSourceTemplate = "{{ .Source }}"
+)
+
+// plainNamePattern matches the object fields which are written into filenames
+// as they are, and invalidNameCharacters matches the characters which are
+// replaced in those which are not.
+var (
+	plainNamePattern      = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+	invalidNameCharacters = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+)
+
+// Output represents the generated output for a set of manifests.
+type Output struct {
+	// Content is the output as it is written without a package.
+	Content string
+
+	// Code is the go source code of the output, without the declaration of its
+	// imports, and Imports are the import paths which it requires.  They form
+	// the go source file which is written when a package is given.
+	Code    string
+	Imports []string
+}
+
+// Generator generates the output for a set of manifests.
+type Generator func(files *manifests.Manifests) (*Output, error)
+
+// Extra generates an additional output, once the outputs of all of the
+// manifests are generated, along with the path of its file relative to the
+// output directory.
+type Extra func() (string, *Output, error)
+
+// Filename represents the fields which are available to the filename template
+// of an output.  The object fields are those of the first object of the output.
+type Filename struct {
+	APIVersion string
+	Kind       string
+	Name       string
+	Namespace  string
+
+	// Source is the base name of the manifest file of the first object, without
+	// its extension.
+	Source string
+
+	// Index is the position of the output, starting from 1.
+	Index int
+}
+
+// File represents a generated output file.
+type File struct {
+	Path    string
+	Content string
+}
+
+// Write generates the output for the manifests and writes it as specified by
+// the options.  When an output directory is given, a file is written for each
+// object, or for each source manifest file when grouping by source, with a name
+// from the filename template.  Otherwise, a single output is written to the
+// output file, or to standard output when none is given.  The extension is used
+// for the default filename templates, which are filled from the first object
+// of each output with its templating resolved by the values.  Extra outputs are
+// only written to an output directory, as the generator includes them in a
+// single output.
+//
+// When checking, nothing is written.  Instead, a unified diff of each output file
+// which differs from the generated output is written to standard output, and an
+// error is returned if any differ.
+//
+// The files written to an output directory are added to the sets, under the
+// name of the output options, so that the files which are no longer generated
+// are removed, or reported as removed when checking, once every set sharing
+// the directory is written.  When no sets are given, this is done for the
+// output directory of the options alone.
+func Write(
+	cliOptions *options.OutputOptions,
+	sets *Sets,
+	files *manifests.Manifests,
+	values map[string]interface{},
+	generate Generator,
+	extension string,
+	extras ...Extra,
+) error {
+	if cliOptions.OutputFile != "" && cliOptions.OutputDir != "" {
+		return ErrOutputConflict
+	}
+
+	if cliOptions.Check && cliOptions.OutputFile == "" && cliOptions.OutputDir == "" {
+		return ErrCheckStdout
+	}
+
+	var outputs []File
+
+	if cliOptions.OutputDir == "" {
+		content, err := generateFile(cliOptions, files, generate)
+		if err != nil {
+			return err
+		}
+
+		if cliOptions.OutputFile == "" {
+			os.Stdout.WriteString(content)
+
+			return nil
+		}
+
+		outputs = []File{{Path: cliOptions.OutputFile, Content: content}}
+	} else {
+		var err error
+		if outputs, err = generateFiles(cliOptions, files, values, generate, extension); err != nil {
+			return err
+		}
+
+		if outputs, err = generateExtras(cliOptions, outputs, extras); err != nil {
+			return err
+		}
+
+		if sets == nil {
+			return writeSet(cliOptions, outputs)
+		}
+
+		sets.Add(cliOptions.OutputDir, cliOptions.Name, outputs)
+	}
+
+	return WriteFiles(outputs, cliOptions.Check)
+}
+
+// writeSet writes, or checks, the outputs of an output directory along with
+// the files which they no longer include.
+func writeSet(cliOptions *options.OutputOptions, outputs []File) error {
+	sets := NewSets()
+	sets.Add(cliOptions.OutputDir, cliOptions.Name, outputs)
+
+	if cliOptions.Check {
+		removed, err := sets.removed()
+		if err != nil {
+			return err
+		}
+
+		return check(os.Stdout, outputs, removed...)
+	}
+
+	if err := WriteFiles(outputs, false); err != nil {
+		return err
+	}
+
+	return sets.Record()
+}
+
+// WriteFiles writes generated output files, or when checking, writes a unified
+// diff of each output file which is not up to date to standard output and
+// returns an error if any are not.
+func WriteFiles(outputs []File, checking bool) error {
+	if checking {
+		return check(os.Stdout, outputs)
+	}
+
+	for _, output := range outputs {
+		if err := WriteFile(output.Path, []byte(output.Content)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// check writes a unified diff of each output file which is missing or differs
+// from its generated content, and of each removed file, which is no longer
+// generated, and returns an error listing them if there are any.
+func check(w io.Writer, outputs []File, removed ...string) error {
+	var stale []string
+
+	for _, output := range outputs {
+		existing, err := os.ReadFile(output.Path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%w; unable to read output file %s", err, output.Path)
+		}
+
+		if err == nil && string(existing) == output.Content {
+			continue
+		}
+
+		from := output.Path
+		if err != nil {
+			from = os.DevNull
+		}
+
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        lines(string(existing)),
+			B:        lines(output.Content),
+			FromFile: from,
+			ToFile:   output.Path,
+			Context:  3,
+		})
+		if err != nil {
+			return fmt.Errorf("%w; unable to compare output file %s", err, output.Path)
+		}
+
+		if _, err := io.WriteString(w, diff); err != nil {
+			return fmt.Errorf("%w; unable to write difference of output file %s", err, output.Path)
+		}
+
+		stale = append(stale, output.Path)
+	}
+
+	for _, path := range removed {
+		existing, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("%w; unable to read output file %s", err, path)
+		}
+
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        lines(string(existing)),
+			FromFile: path,
+			ToFile:   os.DevNull,
+			Context:  3,
+		})
+		if err != nil {
+			return fmt.Errorf("%w; unable to compare output file %s", err, path)
+		}
+
+		if _, err := io.WriteString(w, diff); err != nil {
+			return fmt.Errorf("%w; unable to write difference of output file %s", err, path)
+		}
+
+		stale = append(stale, path+" (no longer generated, remove it)")
+	}
+
+	if len(stale) > 0 {
+		return fmt.Errorf("%w; %s", ErrOutOfDate, strings.Join(stale, ", "))
+	}
+
+	return nil
+}
+
+// Update writes content to a file, or when checking, writes a unified diff to
+// standard output and returns an error if the file is not up to date with it.
+func Update(path, content string, checking bool) error {
+	return WriteFiles([]File{{Path: path, Content: content}}, checking)
+}
+
+// lines splits content into lines for diffing, retaining their line endings.
+func lines(content string) []string {
+	if content == "" {
+		return nil
+	}
+
+	split := strings.SplitAfter(content, "\n")
+	if split[len(split)-1] == "" {
+		split = split[:len(split)-1]
+	}
+
+	return split
+}
+
+// generateFiles generates the files of an output directory.  All files are
+// generated before any are written, so that errors leave the directory as is.
+func generateFiles(
+	cliOptions *options.OutputOptions,
+	files *manifests.Manifests,
+	values map[string]interface{},
+	generate Generator,
+	extension string,
+) ([]File, error) {
+	groups := files.SplitObjects()
+	text := defaultObjectTemplate + extension
+
+	if cliOptions.GroupBySource {
+		groups = files.SplitSources()
+		text = defaultSourceTemplate + extension
+	}
+
+	if cliOptions.OutputTemplate != "" {
+		text = cliOptions.OutputTemplate
+	}
+
+	filenameTemplate, err := template.New("filename").Funcs(sprig.TxtFuncMap()).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%w; %s template %s", err, ErrInvalidFilename, text)
+	}
+
+	outputs := make([]File, 0, len(groups))
+	written := map[string]string{}
+
+	for i, group := range groups {
+		objects := group.ExtractObjects()
+		data := filenameData(objects[0], i+1, values)
+
+		name, err := filename(filenameTemplate, data)
+		if err != nil {
+			return nil, err
+		}
+
+		path := filepath.Join(cliOptions.OutputDir, name)
+
+		if source, ok := written[path]; ok {
+			return nil, fmt.Errorf("%w; %s from %s and %s", ErrDuplicateOutput, path, source, objects[0].Filename)
+		}
+
+		written[path] = objects[0].Filename
+
+		content, err := generateFile(cliOptions, group, generate)
+		if err != nil {
+			return nil, err
+		}
+
+		outputs = append(outputs, File{Path: path, Content: content})
+	}
+
+	return outputs, nil
+}
+
+// generateExtras appends the extra files of an output directory to the files
+// generated for the manifests.
+func generateExtras(cliOptions *options.OutputOptions, outputs []File, extras []Extra) ([]File, error) {
+	for _, extra := range extras {
+		name, generated, err := extra()
+		if err != nil {
+			return nil, err
+		}
+
+		path, err := Join(cliOptions.OutputDir, name)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, output := range outputs {
+			if output.Path == path {
+				return nil, fmt.Errorf("%w; %s", ErrDuplicateOutput, path)
+			}
+		}
+
+		content, err := outputContent(cliOptions, generated)
+		if err != nil {
+			return nil, err
+		}
+
+		outputs = append(outputs, File{Path: path, Content: content})
+	}
+
+	return outputs, nil
+}
+
+// generateFile generates a single output, as a complete go source file when a
+// package is given.
+func generateFile(cliOptions *options.OutputOptions, files *manifests.Manifests, generate Generator) (string, error) {
+	generated, err := generate(files)
+	if err != nil {
+		return "", err
+	}
+
+	return outputContent(cliOptions, generated)
+}
+
+// outputContent returns the content of an output, as a complete go source file
+// when a package is given.
+func outputContent(cliOptions *options.OutputOptions, generated *Output) (string, error) {
+	if cliOptions.Package == "" {
+		return generated.Content, nil
+	}
+
+	return GoFile(cliOptions.Package, generated.Code, generated.Imports...)
+}
+
+// filename returns the name of an output file from the filename template, which
+// must be a relative path within the output directory.
+func filename(filenameTemplate *template.Template, data *Filename) (string, error) {
+	var buf bytes.Buffer
+	if err := filenameTemplate.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("%w; %s", err, ErrInvalidFilename)
+	}
+
+	name := filepath.Clean(strings.TrimSpace(buf.String()))
+
+	if !withinDir(name) {
+		return "", fmt.Errorf("%w %q for object %s/%s; must be a path within the output directory",
+			ErrInvalidFilename, buf.String(), data.Kind, data.Name)
+	}
+
+	return name, nil
+}
+
+// Join joins the name of an output file to the output directory, returning an
+// error if the name is not a relative path within the directory.
+func Join(dir, name string) (string, error) {
+	clean := filepath.Clean(name)
+
+	if !withinDir(clean) {
+		return "", fmt.Errorf("%w %q; must be a path within the output directory", ErrInvalidFilename, name)
+	}
+
+	return filepath.Join(dir, clean), nil
+}
+
+// withinDir determines if a clean path is a relative path within a directory,
+// other than the directory itself.
+func withinDir(name string) bool {
+	return name != "." && name != ".." && !filepath.IsAbs(name) && !strings.HasPrefix(name, ".."+string(filepath.Separator))
+}
+
+// filenameData returns the fields for the filename template of an output, from
+// its first object with any templating resolved by the values.  Fields which
+// are not plain names, such as those which are still templated, are sanitised,
+// or replaced by the index of the output when nothing of them remains, so that
+// they cannot add directories or templating to the filename.
+func filenameData(object *manifests.Object, index int, values map[string]interface{}) *Filename {
+	var fields struct {
+		APIVersion string `yaml:"apiVersion"`
+		Kind       string `yaml:"kind"`
+		Metadata   struct {
+			Name      string `yaml:"name"`
+			Namespace string `yaml:"namespace"`
+		} `yaml:"metadata"`
+	}
+
+	// errors are reported when generating the output
+	_ = yaml.Unmarshal([]byte(resolve(object.Content, values)), &fields)
+
+	fallback := strconv.Itoa(index)
+
+	return &Filename{
+		APIVersion: fields.APIVersion,
+		Kind:       plainName(fields.Kind, fallback),
+		Name:       plainName(fields.Metadata.Name, fallback),
+		Namespace:  plainName(fields.Metadata.Namespace, fallback),
+		Source:     Source(object.Filename),
+		Index:      index,
+	}
+}
+
+// resolve returns the content of an object with its templating resolved by the
+// values, as it is when generating the output, or the content as it is when no
+// values are given or it cannot be resolved.
+func resolve(content string, values map[string]interface{}) string {
+	if values == nil {
+		return content
+	}
+
+	contentTemplate, err := template.New("object").Parse(content)
+	if err != nil {
+		return content
+	}
+
+	var resolved bytes.Buffer
+	if err := contentTemplate.Execute(&resolved, values); err != nil {
+		return content
+	}
+
+	return resolved.String()
+}
+
+// plainName returns a field for a filename, which is returned as it is when it
+// is a plain name.  Otherwise, templating is replaced by the fallback, and any
+// other characters which are not valid in the names of objects are replaced by
+// dashes.  An empty field is left empty.
+func plainName(field, fallback string) string {
+	if field == "" || plainNamePattern.MatchString(field) {
+		return field
+	}
+
+	if strings.Contains(field, "{{") {
+		return fallback
+	}
+
+	sanitised := strings.Trim(invalidNameCharacters.ReplaceAllString(field, "-"), ".-")
+	if sanitised == "" {
+		return fallback
+	}
+
+	return sanitised
+}
+
+// Source returns the base name of a manifest file without its extension, as it
+// is given to filename templates.
+func Source(path string) string {
+	if path == manifests.StdinFilename {
+		return "stdin"
+	}
+
+	base := filepath.Base(path)
+
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// GoFile returns generated go source code, which does not declare its imports,
+// as a complete go source file in a package which declares the imports.  Imports
+// are in the form returned by the generators, as described by code.ImportSpec.
+func GoFile(pkg, source string, imports ...string) (string, error) {
+	imports = uniqueSorted(imports)
+
+	named := map[string]string{}
+
+	for _, imp := range imports {
+		name := path.Base(imp)
+		if space := strings.Index(imp, " "); space >= 0 {
+			name = imp[:space]
+		}
+
+		if existing, ok := named[name]; ok {
+			return "", fmt.Errorf("%w; %q and %q are both imported as %s in package %s",
+				code.ErrConflictingImport, existing, imp, name, pkg)
+		}
+
+		named[name] = imp
+	}
+
+	decl := ""
+
+	if len(imports) == 1 {
+		decl = "import " + code.ImportSpec(imports[0])
+	} else if len(imports) > 1 {
+		decl = "import (\n"
+		for _, imp := range imports {
+			decl += "\t" + code.ImportSpec(imp) + "\n"
+		}
+
+		decl += ")"
+	}
+
+	content := fmt.Sprintf("%s\n\npackage %s\n\n%s\n\n%s", generatedHeader, pkg, decl, source)
+
+	formatted, err := format.Source([]byte(content))
+	if err != nil {
+		return "", fmt.Errorf("%w; unable to format go source file for package %s", err, pkg)
+	}
+
+	return string(formatted), nil
+}
+
+// uniqueSorted removes duplicates from a set of import paths and sorts them.
+func uniqueSorted(paths []string) []string {
+	set := map[string]bool{}
+
+	result := make([]string, 0, len(paths))
+
+	for _, path := range paths {
+		if !set[path] {
+			set[path] = true
+			result = append(result, path)
+		}
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+// WriteFile writes content to a file atomically, by writing it to a temporary
+// file in the same directory and renaming it over the file, so that the file is
+// never left partially written.  Parent directories are created as needed.  A
+// file which already has the content is not written, so that regenerating the
+// output, such as when watching, only changes the modification time of the
+// files whose content changes.
+func WriteFile(path string, content []byte) error {
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, content) {
+		return nil
+	}
+
+	dir := filepath.Dir(path)
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("%w; unable to create directory for output file %s", err, path)
+	}
+
+	temp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("%w; unable to create temporary file for output file %s", err, path)
+	}
+
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(content); err != nil {
+		temp.Close()
+
+		return fmt.Errorf("%w; unable to write output file %s", err, path)
+	}
+
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("%w; unable to write output file %s", err, path)
+	}
+
+	if err := os.Chmod(temp.Name(), 0o644); err != nil { //nolint:gosec
+		return fmt.Errorf("%w; unable to write output file %s", err, path)
+	}
+
+	if err := os.Rename(temp.Name(), path); err != nil {
+		return fmt.Errorf("%w; unable to write output file %s", err, path)
+	}
+
+	return nil
+}
@@ -0,0 +1,507 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package output
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nukleros/gener8s/internal/options"
+	"github.com/nukleros/gener8s/pkg/manifests"
+)
+
+// kinds is a generator which outputs the kinds of the objects of the manifests.
+func kinds(files *manifests.Manifests) (*Output, error) {
+	var kinds []string
+	for _, object := range files.ExtractObjects() {
+		kinds = append(kinds, strings.TrimPrefix(strings.SplitN(object.Content, "\n", 2)[0], "kind: "))
+	}
+
+	return &Output{Content: strings.Join(kinds, ",") + "\n"}, nil
+}
+
+func TestWrite(t *testing.T) {
+	t.Parallel()
+
+	extra := func(path string) Extra {
+		return func() (string, *Output, error) {
+			return path, &Output{Content: "all\n"}, nil
+		}
+	}
+
+	files := manifests.Manifests{
+		{
+			Filename: "config/web.yaml",
+			Content:  []byte("kind: Service\nmetadata:\n  name: web\n---\nkind: Deployment\nmetadata:\n  name: web\n"),
+		},
+		{
+			Filename: "config/db.yaml",
+			Content:  []byte("kind: StatefulSet\nmetadata:\n  name: db\n  namespace: data\n"),
+		},
+	}
+
+	tests := []struct {
+		name       string
+		cliOptions *options.OutputOptions
+		extras     []Extra
+		want       map[string]string
+		wantErr    error
+	}{
+		{
+			name:       "ensure a single output is written to the output file",
+			cliOptions: &options.OutputOptions{OutputFile: "out/all.txt"},
+			want:       map[string]string{"out/all.txt": "Service,Deployment,StatefulSet\n"},
+		},
+		{
+			name:       "ensure a file is written for each object with the default template",
+			cliOptions: &options.OutputOptions{OutputDir: "out"},
+			want: map[string]string{
+				"out/service_web.txt":         "Service\n",
+				"out/deployment_web.txt":      "Deployment\n",
+				"out/data_statefulset_db.txt": "StatefulSet\n",
+			},
+		},
+		{
+			name:       "ensure extra outputs are written to their own file in the output directory",
+			cliOptions: &options.OutputOptions{OutputDir: "out", GroupBySource: true},
+			extras:     []Extra{extra("all.txt")},
+			want: map[string]string{
+				"out/web.txt": "Service,Deployment\n",
+				"out/db.txt":  "StatefulSet\n",
+				"out/all.txt": "all\n",
+			},
+		},
+		{
+			name:       "ensure extra outputs are not written to a single output",
+			cliOptions: &options.OutputOptions{OutputFile: "out/all.txt"},
+			extras:     []Extra{extra("extra.txt")},
+			want:       map[string]string{"out/all.txt": "Service,Deployment,StatefulSet\n"},
+		},
+		{
+			name:       "ensure extra outputs written to the file of another output are rejected",
+			cliOptions: &options.OutputOptions{OutputDir: "out", GroupBySource: true},
+			extras:     []Extra{extra("web.txt")},
+			wantErr:    ErrDuplicateOutput,
+		},
+		{
+			name:       "ensure a file is written for each source manifest file",
+			cliOptions: &options.OutputOptions{OutputDir: "out", GroupBySource: true},
+			want: map[string]string{
+				"out/web.txt": "Service,Deployment\n",
+				"out/db.txt":  "StatefulSet\n",
+			},
+		},
+		{
+			name:       "ensure filename templates may use the index and create subdirectories",
+			cliOptions: &options.OutputOptions{OutputDir: "out", OutputTemplate: "{{ .Source }}/{{ .Index }}.txt"},
+			want: map[string]string{
+				"out/web/1.txt": "Service\n",
+				"out/web/2.txt": "Deployment\n",
+				"out/db/3.txt":  "StatefulSet\n",
+			},
+		},
+		{
+			name:       "ensure outputs written to the same file are rejected",
+			cliOptions: &options.OutputOptions{OutputDir: "out", OutputTemplate: "{{ .Name }}.txt"},
+			wantErr:    ErrDuplicateOutput,
+		},
+		{
+			name:       "ensure outputs outside of the output directory are rejected",
+			cliOptions: &options.OutputOptions{OutputDir: "out", OutputTemplate: "../{{ .Name }}.txt"},
+			wantErr:    ErrInvalidFilename,
+		},
+		{
+			name:       "ensure both an output file and directory are rejected",
+			cliOptions: &options.OutputOptions{OutputFile: "all.txt", OutputDir: "out"},
+			wantErr:    ErrOutputConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+
+			cliOptions := *tt.cliOptions
+			if cliOptions.OutputFile != "" {
+				cliOptions.OutputFile = filepath.Join(dir, cliOptions.OutputFile)
+			}
+
+			if cliOptions.OutputDir != "" {
+				cliOptions.OutputDir = filepath.Join(dir, cliOptions.OutputDir)
+			}
+
+			err := Write(&cliOptions, nil, &files, nil, kinds, ".txt", tt.extras...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Write() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			got := map[string]string{}
+
+			if walkErr := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+				// the list of the generated files is covered by TestSets_sharedDir
+				if err != nil || info.IsDir() || info.Name() == ListFilename {
+					return err
+				}
+
+				content, err := os.ReadFile(path)
+				if err != nil {
+					return err
+				}
+
+				relative, err := filepath.Rel(dir, path)
+				got[filepath.ToSlash(relative)] = string(content)
+
+				return err
+			}); walkErr != nil {
+				t.Fatal(walkErr)
+			}
+
+			if tt.wantErr != nil {
+				assert.Empty(t, got)
+
+				return
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGoFile(t *testing.T) {
+	t.Parallel()
+
+	const unstructuredImport = "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	got, err := GoFile("web", "var object = &unstructured.Unstructured{}\n", unstructuredImport)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, generatedHeader+`
+
+package web
+
+import "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+var object = &unstructured.Unstructured{}
+`, got)
+
+	got, err = GoFile("web", "var objects = []client.Object{}\n", "sigs.k8s.io/controller-runtime/pkg/client")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, generatedHeader+`
+
+package web
+
+import "sigs.k8s.io/controller-runtime/pkg/client"
+
+var objects = []client.Object{}
+`, got)
+
+	got, err = GoFile("web", "var object = &unstructured.Unstructured{}\n", unstructuredImport, "fmt", unstructuredImport)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, generatedHeader+`
+
+package web
+
+import (
+	"fmt"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var object = &unstructured.Unstructured{}
+`, got)
+}
+
+func Test_filenameData(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		values  map[string]interface{}
+		want    *Filename
+	}{
+		{
+			name:    "ensure fields are taken from the object",
+			content: "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n  namespace: shop\n",
+			want:    &Filename{APIVersion: "v1", Kind: "Service", Name: "web", Namespace: "shop", Source: "web", Index: 2},
+		},
+		{
+			name:    "ensure fields are taken from the object after the values are applied",
+			content: "kind: Service\nmetadata:\n  name: '{{ .Name }}'\n",
+			values:  map[string]interface{}{"Name": "frontend"},
+			want:    &Filename{Kind: "Service", Name: "frontend", Source: "web", Index: 2},
+		},
+		{
+			name:    "ensure templated fields fall back to the index",
+			content: "kind: Service\nmetadata:\n  name: '{{ .Name }}'\n",
+			want:    &Filename{Kind: "Service", Name: "2", Source: "web", Index: 2},
+		},
+		{
+			name:    "ensure path characters are sanitised",
+			content: "kind: Service\nmetadata:\n  name: ../web/api\n  namespace: ..\n",
+			want:    &Filename{Kind: "Service", Name: "web-api", Namespace: "2", Source: "web", Index: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			object := &manifests.Object{Filename: "config/web.yaml", Content: tt.content}
+			assert.Equal(t, tt.want, filenameData(object, 2, tt.values))
+		})
+	}
+}
+
+func TestJoin(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		file    string
+		want    string
+		wantErr bool
+	}{
+		{name: "ensure a file is joined to the directory", file: "web.yaml", want: filepath.Join("out", "web.yaml")},
+		{name: "ensure a nested file is joined to the directory", file: "apps/../web/web.yaml", want: filepath.Join("out", "web", "web.yaml")},
+		{name: "ensure an empty name is rejected", file: "", wantErr: true},
+		{name: "ensure an absolute path is rejected", file: "/etc/passwd", wantErr: true},
+		{name: "ensure a path outside the directory is rejected", file: "../web.yaml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Join("out", tt.file)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidFilename)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	current := filepath.Join(dir, "current.txt")
+	stale := filepath.Join(dir, "stale.txt")
+	missing := filepath.Join(dir, "missing.txt")
+
+	for path, content := range map[string]string{current: "Service\n", stale: "Service\nDeployment\n"} {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		outputs []File
+		want    string
+		wantErr error
+	}{
+		{
+			name:    "ensure up to date outputs pass",
+			outputs: []File{{Path: current, Content: "Service\n"}},
+			want:    "",
+			wantErr: nil,
+		},
+		{
+			name:    "ensure a diff is written for stale outputs",
+			outputs: []File{{Path: current, Content: "Service\n"}, {Path: stale, Content: "Service\nStatefulSet\n"}},
+			want: "--- " + stale + "\n+++ " + stale + "\n@@ -1,2 +1,2 @@\n" +
+				" Service\n-Deployment\n+StatefulSet\n",
+			wantErr: ErrOutOfDate,
+		},
+		{
+			name:    "ensure a diff is written for missing outputs",
+			outputs: []File{{Path: missing, Content: "Service\n"}},
+			want:    "--- " + os.DevNull + "\n+++ " + missing + "\n@@ -0,0 +1 @@\n+Service\n",
+			wantErr: ErrOutOfDate,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got strings.Builder
+
+			err := check(&got, tt.outputs)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			assert.Equal(t, tt.want, got.String())
+		})
+	}
+}
+
+func TestWrite_Check(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "all.txt")
+
+	if err := os.WriteFile(path, []byte("Service\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	files := manifests.Manifests{{Filename: "web.yaml", Content: []byte("kind: Service\n")}}
+
+	assert.NoError(t, Write(&options.OutputOptions{OutputFile: path, Check: true}, nil, &files, nil, kinds, ".txt"))
+	assert.ErrorIs(t, Write(&options.OutputOptions{Check: true}, nil, &files, nil, kinds, ".txt"), ErrCheckStdout)
+	assert.NoFileExists(t, filepath.Join(dir, "missing.txt"))
+
+	// files which were generated but are no longer generated are reported, and
+	// are removed when writing, while other files are left alone
+	outputDir := filepath.Join(dir, "out")
+	both := manifests.Manifests{{Filename: "web.yaml", Content: []byte("kind: Service\n---\nkind: Deployment\n")}}
+	outputOptions := options.OutputOptions{OutputDir: outputDir, OutputTemplate: "{{ .Kind | lower }}.txt"}
+
+	assert.NoError(t, Write(&outputOptions, nil, &both, nil, kinds, ".txt"))
+
+	handwritten := filepath.Join(outputDir, "handwritten.go")
+	if err := os.WriteFile(handwritten, []byte(generatedHeader+"\n\npackage web\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	checkOptions := outputOptions
+	checkOptions.Check = true
+
+	assert.NoError(t, Write(&checkOptions, nil, &both, nil, kinds, ".txt"))
+
+	removed := filepath.Join(outputDir, "deployment.txt")
+
+	err := Write(&checkOptions, nil, &files, nil, kinds, ".txt")
+	assert.ErrorIs(t, err, ErrOutOfDate)
+	assert.ErrorContains(t, err, removed)
+	assert.NotContains(t, err.Error(), "handwritten.go")
+	assert.FileExists(t, removed)
+
+	assert.NoError(t, Write(&outputOptions, nil, &files, nil, kinds, ".txt"))
+	assert.NoFileExists(t, removed)
+	assert.FileExists(t, handwritten)
+	assert.NoError(t, Write(&checkOptions, nil, &files, nil, kinds, ".txt"))
+}
+
+func TestSets_sharedDir(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	web := manifests.Manifests{{Filename: "web.yaml", Content: []byte("kind: Service\n---\nkind: Deployment\n")}}
+	db := manifests.Manifests{{Filename: "db.yaml", Content: []byte("kind: StatefulSet\n")}}
+
+	write := func(check bool, sets *Sets, name string, files manifests.Manifests) error {
+		return Write(&options.OutputOptions{
+			Name:           name,
+			OutputDir:      dir,
+			OutputTemplate: "{{ .Kind | lower }}.txt",
+			Check:          check,
+		}, sets, &files, nil, kinds, ".txt")
+	}
+
+	run := func(check bool, web, db manifests.Manifests) error {
+		sets := NewSets()
+
+		for name, files := range map[string]manifests.Manifests{"web": web, "db": db} {
+			if err := write(check, sets, name, files); err != nil {
+				return err
+			}
+		}
+
+		if check {
+			return sets.Check(io.Discard)
+		}
+
+		return sets.Record()
+	}
+
+	// sets sharing a directory do not report the files of each other
+	assert.NoError(t, run(false, web, db))
+	assert.NoError(t, run(true, web, db))
+	assert.NoError(t, write(true, nil, "db", db))
+
+	// a file which moves from one set to another is not removed
+	moved := manifests.Manifests{{Filename: "db.yaml", Content: []byte("kind: StatefulSet\n---\nkind: Deployment\n")}}
+	service := manifests.Manifests{{Filename: "web.yaml", Content: []byte("kind: Service\n")}}
+
+	assert.NoError(t, run(false, service, moved))
+	assert.FileExists(t, filepath.Join(dir, "deployment.txt"))
+	assert.NoError(t, run(true, service, moved))
+
+	// a file which is no longer generated by any set is reported and removed
+	err := run(true, service, db)
+	assert.ErrorIs(t, err, ErrOutOfDate)
+	assert.ErrorContains(t, err, filepath.Join(dir, "deployment.txt"))
+
+	assert.NoError(t, run(false, service, db))
+	assert.NoFileExists(t, filepath.Join(dir, "deployment.txt"))
+	assert.FileExists(t, filepath.Join(dir, "service.txt"))
+	assert.FileExists(t, filepath.Join(dir, "statefulset.txt"))
+}
+
+func TestWriteFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "out", "web.yaml")
+
+	if err := WriteFile(path, []byte("kind: Service\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	modified := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(path, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+
+	// unchanged content is not rewritten
+	if err := WriteFile(path, []byte("kind: Service\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, info.ModTime().Equal(modified))
+
+	if err := WriteFile(path, []byte("kind: Deployment\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "kind: Deployment\n", string(content))
+}
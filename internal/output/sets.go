@@ -0,0 +1,238 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package output
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ListFilename is the name of the file in an output directory which lists the
+// files generated into the directory, by the name of the set of outputs which
+// generated them, so that the files which are no longer generated can be found
+// without touching those of other sets, or of other tools, in the directory.
+const ListFilename = ".gener8s-outputs.yaml"
+
+// listHeader is the header of the list of the files generated into an output
+// directory, which marks it as generated.
+const listHeader = "# Code generated by gener8s. DO NOT EDIT."
+
+// Sets collects the files generated into output directories by several sets of
+// outputs, such as the jobs of a project config file, so that the files which
+// are no longer generated are found once all of the sets sharing a directory
+// are generated.  A file which one set stops generating and another starts
+// generating is therefore not removed.
+type Sets struct {
+	dirs map[string]map[string][]string
+}
+
+// NewSets returns an empty collection of sets of outputs.
+func NewSets() *Sets {
+	return &Sets{dirs: map[string]map[string][]string{}}
+}
+
+// Add adds the files generated by a named set of outputs into an output
+// directory.
+func (sets *Sets) Add(dir, name string, outputs []File) {
+	dir = filepath.Clean(dir)
+
+	if sets.dirs[dir] == nil {
+		sets.dirs[dir] = map[string][]string{}
+	}
+
+	if sets.dirs[dir][name] == nil {
+		sets.dirs[dir][name] = []string{}
+	}
+
+	for _, output := range outputs {
+		sets.dirs[dir][name] = append(sets.dirs[dir][name], filepath.Clean(output.Path))
+	}
+}
+
+// removed returns the paths of the files which the list of each output
+// directory records as generated by one of the sets, but which none of the
+// sets generate now and no other set in the list generates, and which still
+// exist.
+func (sets *Sets) removed() ([]string, error) {
+	var removed []string
+
+	for _, dir := range sets.sortedDirs() {
+		list, err := readList(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		stale, err := sets.stale(dir, list)
+		if err != nil {
+			return nil, err
+		}
+
+		removed = append(removed, stale...)
+	}
+
+	return removed, nil
+}
+
+// Check writes a unified diff of each file which is no longer generated to a
+// writer, and returns an error listing them if there are any.
+func (sets *Sets) Check(w io.Writer) error {
+	removed, err := sets.removed()
+	if err != nil {
+		return err
+	}
+
+	return check(w, nil, removed...)
+}
+
+// Record removes the files which are no longer generated, and records the files generated by each of the sets in the list of
+// each output directory.
+func (sets *Sets) Record() error {
+	for _, dir := range sets.sortedDirs() {
+		list, err := readList(dir)
+		if err != nil {
+			return err
+		}
+
+		stale, err := sets.stale(dir, list)
+		if err != nil {
+			return err
+		}
+
+		for _, path := range stale {
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("%w; unable to remove output file %s", err, path)
+			}
+		}
+
+		for name, paths := range sets.dirs[dir] {
+			list[name] = relativePaths(dir, paths)
+		}
+
+		if err := writeList(dir, list); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// stale returns the files of an output directory which its list records as
+// generated by one of the sets, but which are no longer generated.
+func (sets *Sets) stale(dir string, list map[string][]string) ([]string, error) {
+	generated := map[string]bool{}
+
+	for _, paths := range sets.dirs[dir] {
+		for _, path := range paths {
+			generated[path] = true
+		}
+	}
+
+	// files of other sets in the list are not removed, as they may now generate
+	// a file which one of the sets no longer does
+	for name, names := range list {
+		if _, ok := sets.dirs[dir][name]; ok {
+			continue
+		}
+
+		for _, listed := range names {
+			generated[filepath.Join(dir, filepath.FromSlash(listed))] = true
+		}
+	}
+
+	var stale []string
+
+	for name := range sets.dirs[dir] {
+		for _, listed := range list[name] {
+			path := filepath.Join(dir, filepath.FromSlash(listed))
+
+			if generated[path] {
+				continue
+			}
+
+			generated[path] = true
+
+			if _, err := os.Stat(path); err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					continue
+				}
+
+				return nil, fmt.Errorf("%w; unable to read output file %s", err, path)
+			}
+
+			stale = append(stale, path)
+		}
+	}
+
+	sort.Strings(stale)
+
+	return stale, nil
+}
+
+// sortedDirs returns the output directories of the sets in order.
+func (sets *Sets) sortedDirs() []string {
+	dirs := make([]string, 0, len(sets.dirs))
+	for dir := range sets.dirs {
+		dirs = append(dirs, dir)
+	}
+
+	sort.Strings(dirs)
+
+	return dirs
+}
+
+// relativePaths returns the paths of files within a directory relative to the
+// directory, using forward slashes, in order.
+func relativePaths(dir string, paths []string) []string {
+	relative := make([]string, 0, len(paths))
+
+	for _, path := range paths {
+		if rel, err := filepath.Rel(dir, path); err == nil {
+			relative = append(relative, filepath.ToSlash(rel))
+		}
+	}
+
+	sort.Strings(relative)
+
+	return relative
+}
+
+// readList reads the list of the files generated into an output directory, by
+// the name of their set and relative to the directory with forward slashes,
+// which is empty when the directory has no list.
+func readList(dir string) (map[string][]string, error) {
+	path := filepath.Join(dir, ListFilename)
+	list := map[string][]string{}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return list, nil
+		}
+
+		return nil, fmt.Errorf("%w; unable to read output list %s", err, path)
+	}
+
+	if err := yaml.Unmarshal(content, &list); err != nil {
+		return nil, fmt.Errorf("%w; invalid output list %s", err, path)
+	}
+
+	return list, nil
+}
+
+// writeList writes the list of the files generated into an output directory.
+func writeList(dir string, list map[string][]string) error {
+	content, err := yaml.Marshal(list)
+	if err != nil {
+		return fmt.Errorf("%w; unable to encode output list", err)
+	}
+
+	return WriteFile(filepath.Join(dir, ListFilename), append([]byte(listHeader+"\n"), content...))
+}
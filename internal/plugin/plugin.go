@@ -0,0 +1,255 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/nukleros/gener8s/internal/output"
+	"github.com/nukleros/gener8s/pkg/manifests"
+)
+
+var (
+	ErrPluginFailed    = errors.New("plugin failed")
+	ErrInvalidResponse = errors.New("invalid plugin response")
+)
+
+// Prefix is the prefix of the names of plugin executables, which are run as the
+// gener8s subcommand named after the rest of their name.
+const Prefix = "gener8s-"
+
+// APIVersion is the version of the protocol between gener8s and its plugins.
+const APIVersion = "gener8s.nukleros.io/v1"
+
+// Plugin represents a generator plugin executable.
+type Plugin struct {
+	// Name is the name of the plugin, which is the name of the executable
+	// without the prefix.
+	Name string
+
+	// Path is the path to the executable.
+	Path string
+}
+
+// Request represents the input of a plugin, which is written to its standard
+// input as json.
+type Request struct {
+	APIVersion string `json:"apiVersion"`
+
+	// Args are the arguments given to the plugin subcommand.
+	Args []string `json:"args,omitempty"`
+
+	// Values are the values from the values file, which resolve any templating
+	// in the content of the objects.
+	Values map[string]interface{} `json:"values,omitempty"`
+
+	// Objects are the objects of the manifests, in order.
+	Objects []*Object `json:"objects"`
+}
+
+// Object represents an object of the manifests along with its position.
+type Object struct {
+	// Filename is the manifest file the object was extracted from.
+	Filename string `json:"filename"`
+
+	// Document is the position of the object within the manifest file, starting
+	// from 1.
+	Document int `json:"document"`
+
+	// Line is the line the object starts on within the manifest file, starting
+	// from 1.
+	Line int `json:"line"`
+
+	// Content is the yaml content of the object, as it is in the manifest file.
+	Content string `json:"content"`
+
+	// Object is the decoded object.
+	Object json.RawMessage `json:"object"`
+}
+
+// Response represents the output of a plugin, which it writes to its standard
+// output as json.
+type Response struct {
+	// Files are the files to write, with paths relative to the output
+	// directory.
+	Files []*File `json:"files"`
+}
+
+// File represents a file which is generated by a plugin.
+type File struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// Discover returns the plugins on a search path, such as the PATH environment
+// variable, in order of their names.  As with kubectl plugins, the first
+// executable with a name shadows any later executables with the same name.
+// Directories which cannot be read are skipped.
+func Discover(searchPath string) []*Plugin {
+	var plugins []*Plugin
+
+	found := map[string]bool{}
+
+	for _, dir := range filepath.SplitList(searchPath) {
+		if dir == "" {
+			continue
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			name := pluginName(entry.Name())
+			path := filepath.Join(dir, entry.Name())
+
+			if name == "" || found[name] || !isExecutable(path) {
+				continue
+			}
+
+			found[name] = true
+
+			plugins = append(plugins, &Plugin{Name: name, Path: path})
+		}
+	}
+
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+
+	return plugins
+}
+
+// Lookup returns the plugin with a name, found on the PATH as exec.LookPath
+// finds executables, or false if there is none.  Unlike Discover, no directories
+// are read, so plugins can be resolved on every invocation.
+func Lookup(name string) (*Plugin, bool) {
+	if strings.ContainsAny(name, `/\`) || pluginName(Prefix+name) != name {
+		return nil, false
+	}
+
+	path, err := exec.LookPath(Prefix + name)
+	if err != nil {
+		return nil, false
+	}
+
+	return &Plugin{Name: name, Path: path}, true
+}
+
+// pluginName returns the name of a plugin from the name of its executable, or
+// an empty name if the executable is not a plugin.
+func pluginName(filename string) string {
+	if runtime.GOOS == "windows" {
+		filename = strings.TrimSuffix(filename, ".exe")
+	}
+
+	name := strings.TrimPrefix(filename, Prefix)
+	if name == filename || name == "" || strings.HasPrefix(name, "-") {
+		return ""
+	}
+
+	return name
+}
+
+// isExecutable determines if a path is an executable file, following any
+// symlinks.  All files are executable on windows.
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+
+	return runtime.GOOS == "windows" || info.Mode()&0o111 != 0
+}
+
+// NewRequest returns the request for the objects of a set of manifests.
+func NewRequest(files *manifests.Manifests, values map[string]interface{}, args ...string) (*Request, error) {
+	request := &Request{APIVersion: APIVersion, Args: args, Values: values, Objects: []*Object{}}
+
+	for _, object := range files.ExtractObjects() {
+		decoded, err := yaml.YAMLToJSON([]byte(object.Content))
+		if err != nil {
+			return nil, object.Wrap(manifests.YAMLError(fmt.Errorf("unable to decode object, %w", err)))
+		}
+
+		request.Objects = append(request.Objects, &Object{
+			Filename: object.Filename,
+			Document: object.Document,
+			Line:     object.Line,
+			Content:  object.Content,
+			Object:   decoded,
+		})
+	}
+
+	return request, nil
+}
+
+// Run runs the plugin with a request, returning its response.  The standard
+// error of the plugin is written to stderr.
+func (plugin *Plugin) Run(ctx context.Context, request *Request, stderr io.Writer) (*Response, error) {
+	input, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("%w; unable to encode request for plugin %s", err, plugin.Name)
+	}
+
+	var stdout bytes.Buffer
+
+	//nolint:gosec
+	cmd := exec.CommandContext(ctx, plugin.Path, request.Args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%w %s; %s", ErrPluginFailed, plugin.Name, err)
+	}
+
+	var response Response
+
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return nil, fmt.Errorf("%w from plugin %s; %s", ErrInvalidResponse, plugin.Name, err)
+	}
+
+	return &response, nil
+}
+
+// Outputs returns the files of the response as output files within the output
+// directory.
+func (response *Response) Outputs(dir string) ([]output.File, error) {
+	files := make([]output.File, 0, len(response.Files))
+	written := map[string]bool{}
+
+	for _, file := range response.Files {
+		if file == nil {
+			continue
+		}
+
+		path, err := output.Join(dir, file.Path)
+		if err != nil {
+			return nil, fmt.Errorf("%w; %s", err, ErrInvalidResponse)
+		}
+
+		if written[path] {
+			return nil, fmt.Errorf("%w; %s", output.ErrDuplicateOutput, path)
+		}
+
+		written[path] = true
+
+		files = append(files, output.File{Path: path, Content: file.Content})
+	}
+
+	return files, nil
+}
@@ -0,0 +1,268 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nukleros/gener8s/internal/output"
+	"github.com/nukleros/gener8s/pkg/manifests"
+)
+
+// writeExecutable writes an executable file for a test.
+func writeExecutable(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(content), 0o755); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are discovered by their executable bit")
+	}
+
+	first, second := t.TempDir(), t.TempDir()
+
+	writeExecutable(t, filepath.Join(first, "gener8s-policy"), "#!/bin/sh\n")
+	writeExecutable(t, filepath.Join(first, "kubectl-policy"), "#!/bin/sh\n")
+	writeExecutable(t, filepath.Join(first, "gener8s-"), "#!/bin/sh\n")
+	writeExecutable(t, filepath.Join(second, "gener8s-policy"), "#!/bin/sh\n")
+	writeExecutable(t, filepath.Join(second, "gener8s-dsl"), "#!/bin/sh\n")
+
+	if err := os.WriteFile(filepath.Join(second, "gener8s-readme"), []byte("not a plugin"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Mkdir(filepath.Join(second, "gener8s-dir"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	searchPath := strings.Join([]string{first, filepath.Join(first, "missing"), second}, string(os.PathListSeparator))
+
+	assert.Equal(t, []*Plugin{
+		{Name: "dsl", Path: filepath.Join(second, "gener8s-dsl")},
+		{Name: "policy", Path: filepath.Join(first, "gener8s-policy")},
+	}, Discover(searchPath))
+}
+
+//nolint:paralleltest // the PATH is set for the test
+func TestLookup(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are found by their executable bit")
+	}
+
+	first, second := t.TempDir(), t.TempDir()
+
+	writeExecutable(t, filepath.Join(first, "gener8s-policy"), "#!/bin/sh\n")
+	writeExecutable(t, filepath.Join(second, "gener8s-policy"), "#!/bin/sh\n")
+	writeExecutable(t, filepath.Join(second, "gener8s-dsl"), "#!/bin/sh\n")
+
+	t.Setenv("PATH", strings.Join([]string{first, second}, string(os.PathListSeparator)))
+
+	tests := []struct {
+		name   string
+		plugin string
+		want   *Plugin
+	}{
+		{
+			name:   "ensure the first plugin on the path is found",
+			plugin: "policy",
+			want:   &Plugin{Name: "policy", Path: filepath.Join(first, "gener8s-policy")},
+		},
+		{
+			name:   "ensure plugins in later directories are found",
+			plugin: "dsl",
+			want:   &Plugin{Name: "dsl", Path: filepath.Join(second, "gener8s-dsl")},
+		},
+		{
+			name:   "ensure missing plugins are not found",
+			plugin: "missing",
+		},
+		{
+			name:   "ensure names with a path are not found",
+			plugin: "../gener8s-policy",
+		},
+		{
+			name:   "ensure names which are flags are not found",
+			plugin: "-policy",
+		},
+	}
+
+	for _, tt := range tests {
+		got, ok := Lookup(tt.plugin)
+		assert.Equal(t, tt.want != nil, ok, tt.name)
+		assert.Equal(t, tt.want, got, tt.name)
+	}
+}
+
+func TestNewRequest(t *testing.T) {
+	t.Parallel()
+
+	files := manifests.Manifests{{
+		Filename: "web.yaml",
+		Content:  []byte("kind: Service\nmetadata:\n  name: web\n---\nkind: ConfigMap\nmetadata:\n  name: web\n"),
+	}}
+
+	got, err := NewRequest(&files, map[string]interface{}{"replicas": 3}, "--strict")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, APIVersion, got.APIVersion)
+	assert.Equal(t, []string{"--strict"}, got.Args)
+	assert.Equal(t, map[string]interface{}{"replicas": 3}, got.Values)
+
+	if assert.Len(t, got.Objects, 2) {
+		assert.Equal(t, "web.yaml", got.Objects[1].Filename)
+		assert.Equal(t, 2, got.Objects[1].Document)
+		assert.Equal(t, 5, got.Objects[1].Line)
+		assert.JSONEq(t, `{"kind":"ConfigMap","metadata":{"name":"web"}}`, string(got.Objects[1].Object))
+	}
+}
+
+func TestNewRequest_invalid(t *testing.T) {
+	t.Parallel()
+
+	files := manifests.Manifests{{
+		Filename: "web.yaml",
+		Content:  []byte("kind: Service\n---\nkind: ConfigMap\nmetadata: [\n"),
+	}}
+
+	_, err := NewRequest(&files, nil)
+	assert.ErrorContains(t, err, "web.yaml:4:")
+}
+
+func TestPlugin_Run(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin fixtures are shell scripts")
+	}
+
+	dir := t.TempDir()
+
+	// the plugin returns a file with the content of the object, and logs its arguments
+	echoPlugin := filepath.Join(dir, "gener8s-echo")
+	writeExecutable(t, echoPlugin, `#!/bin/sh
+echo "running with $*" >&2
+printf '%s\n' '{"files":[{"path":"objects.txt","content":'"$(cat | tr -d '\n' | sed 's/.*"content":\("[^"]*"\).*/\1/')"'}]}'
+`)
+
+	failPlugin := filepath.Join(dir, "gener8s-fail")
+	writeExecutable(t, failPlugin, "#!/bin/sh\nexit 3\n")
+
+	invalidPlugin := filepath.Join(dir, "gener8s-invalid")
+	writeExecutable(t, invalidPlugin, "#!/bin/sh\necho 'files:'\n")
+
+	request := &Request{
+		APIVersion: APIVersion,
+		Args:       []string{"--strict"},
+		Objects:    []*Object{{Filename: "web.yaml", Content: "kind: Service", Object: json.RawMessage(`{}`)}},
+	}
+
+	tests := []struct {
+		name       string
+		plugin     *Plugin
+		want       *Response
+		wantStderr string
+		wantErr    error
+	}{
+		{
+			name:       "ensure the plugin is run with the request and its response is returned",
+			plugin:     &Plugin{Name: "echo", Path: echoPlugin},
+			want:       &Response{Files: []*File{{Path: "objects.txt", Content: "kind: Service"}}},
+			wantStderr: "running with --strict\n",
+		},
+		{
+			name:    "ensure a failed plugin returns an error",
+			plugin:  &Plugin{Name: "fail", Path: failPlugin},
+			wantErr: ErrPluginFailed,
+		},
+		{
+			name:    "ensure an invalid response returns an error",
+			plugin:  &Plugin{Name: "invalid", Path: invalidPlugin},
+			wantErr: ErrInvalidResponse,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var stderr bytes.Buffer
+
+			got, err := tt.plugin.Run(context.Background(), request, &stderr)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantStderr, stderr.String())
+		})
+	}
+}
+
+func TestResponse_Outputs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		response *Response
+		want     []output.File
+		wantErr  error
+	}{
+		{
+			name:     "ensure files are written within the output directory",
+			response: &Response{Files: []*File{{Path: "policy/web.yaml", Content: "web"}}},
+			want:     []output.File{{Path: filepath.Join("out", "policy", "web.yaml"), Content: "web"}},
+		},
+		{
+			name:     "ensure files outside the output directory are rejected",
+			response: &Response{Files: []*File{{Path: "../web.yaml"}}},
+			wantErr:  output.ErrInvalidFilename,
+		},
+		{
+			name:     "ensure files written more than once are rejected",
+			response: &Response{Files: []*File{{Path: "web.yaml"}, {Path: "./web.yaml"}}},
+			wantErr:  output.ErrDuplicateOutput,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := tt.response.Outputs("out")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
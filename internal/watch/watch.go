@@ -0,0 +1,238 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package watch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Debounce is how long the watcher waits after a change for further changes,
+// so that a burst of changes, such as saving several files at once, regenerates
+// each output only once.
+const Debounce = 200 * time.Millisecond
+
+// Target represents an output which is regenerated when its inputs change.
+type Target struct {
+	// Name identifies the target in the messages of the watcher.
+	Name string
+
+	// Inputs returns the files the output is generated from, along with the
+	// directories in which files may be added or removed to change them.  They
+	// are resolved again after each change, so that new files matching globs are
+	// picked up.
+	Inputs func() (files, dirs []string, err error)
+
+	// Run generates the output.
+	Run func() error
+}
+
+// target represents the state of a target while watching.
+type target struct {
+	*Target
+
+	files map[string]bool
+	dirs  map[string]bool
+}
+
+// Watcher regenerates targets when their inputs change.
+type Watcher struct {
+	targets  []*target
+	watcher  *fsnotify.Watcher
+	watched  map[string]bool
+	log      io.Writer
+	debounce time.Duration
+}
+
+// Run runs the targets, then watches their inputs and regenerates them when
+// they change, until interrupted.  Messages and errors are written to standard
+// error, so that errors are reported without exiting.
+func Run(targets ...*Target) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return Watch(ctx, os.Stderr, Debounce, targets...)
+}
+
+// Watch runs the targets, then watches their inputs and regenerates the targets
+// whose inputs change, until the context is done.  Changes are debounced, and
+// messages and errors are written to the log.
+func Watch(ctx context.Context, log io.Writer, debounce time.Duration, targets ...*Target) error {
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("%w; unable to watch files", err)
+	}
+
+	defer fsWatcher.Close()
+
+	watcher := &Watcher{
+		watcher:  fsWatcher,
+		watched:  map[string]bool{},
+		log:      log,
+		debounce: debounce,
+	}
+
+	for _, t := range targets {
+		// inputs which can never be watched, such as standard input, are fatal
+		if _, _, err := t.Inputs(); err != nil {
+			return fmt.Errorf("%w; unable to watch %s", err, t.Name)
+		}
+
+		watcher.targets = append(watcher.targets, &target{Target: t})
+	}
+
+	for _, t := range watcher.targets {
+		watcher.run(t)
+	}
+
+	fmt.Fprintln(log, "watching for changes, press ctrl+c to stop")
+
+	return watcher.loop(ctx)
+}
+
+// loop collects changes until they stop for the debounce duration, then
+// regenerates the targets affected by them.
+func (watcher *Watcher) loop(ctx context.Context) error {
+	changes := map[string]bool{}
+
+	var settled <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+
+			changes[absolute(event.Name)] = true
+			settled = time.After(watcher.debounce)
+		case err, ok := <-watcher.watcher.Errors:
+			if !ok {
+				return nil
+			}
+
+			fmt.Fprintf(watcher.log, "error watching files: %s\n", err)
+		case <-settled:
+			for _, t := range watcher.targets {
+				if t.affected(changes) {
+					watcher.run(t)
+				}
+			}
+
+			changes = map[string]bool{}
+			settled = nil
+		}
+	}
+}
+
+// run generates a target, reporting the result, and then watches its inputs.
+func (watcher *Watcher) run(t *target) {
+	if err := t.Run(); err != nil {
+		fmt.Fprintf(watcher.log, "%s: %s\n", t.Name, err)
+	} else {
+		fmt.Fprintf(watcher.log, "%s: generated at %s\n", t.Name, time.Now().Format("15:04:05"))
+	}
+
+	files, dirs, err := t.Inputs()
+	if err != nil {
+		fmt.Fprintf(watcher.log, "%s: %s\n", t.Name, err)
+
+		return
+	}
+
+	t.files, t.dirs = set(files), set(dirs)
+
+	for dir := range t.dirs {
+		if watcher.watched[dir] {
+			continue
+		}
+
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+
+		if err := watcher.watcher.Add(dir); err != nil {
+			fmt.Fprintf(watcher.log, "%s: unable to watch %s: %s\n", t.Name, dir, err)
+
+			continue
+		}
+
+		watcher.watched[dir] = true
+	}
+}
+
+// affected determines if a target is affected by changes, which is the case
+// when one of its input files changed, or a change within one of its
+// directories changes its input files, such as a new file matching a glob.
+func (t *target) affected(changes map[string]bool) bool {
+	changedDir := false
+
+	for path := range changes {
+		if t.files[path] {
+			return true
+		}
+
+		if t.dirs[path] || t.dirs[filepath.Dir(path)] {
+			changedDir = true
+		}
+	}
+
+	if !changedDir {
+		return false
+	}
+
+	files, _, err := t.Inputs()
+	if err != nil {
+		return true
+	}
+
+	current := set(files)
+	if len(current) != len(t.files) {
+		return true
+	}
+
+	for file := range current {
+		if !t.files[file] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// set returns the absolute paths of a list of paths as a set.
+func set(paths []string) map[string]bool {
+	pathSet := make(map[string]bool, len(paths))
+
+	for _, path := range paths {
+		pathSet[absolute(path)] = true
+	}
+
+	return pathSet
+}
+
+// absolute returns the absolute form of a path, or the clean path if it cannot
+// be determined.
+func absolute(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+
+	return abs
+}
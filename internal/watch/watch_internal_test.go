@@ -0,0 +1,103 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package watch
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testTarget is a target whose inputs are the yaml files of a directory, which
+// counts its runs.
+type testTarget struct {
+	dir  string
+	mu   sync.Mutex
+	runs int
+}
+
+func (tt *testTarget) target(name string) *Target {
+	return &Target{
+		Name: name,
+		Inputs: func() ([]string, []string, error) {
+			files, err := filepath.Glob(filepath.Join(tt.dir, "*.yaml"))
+
+			return files, []string{tt.dir}, err
+		},
+		Run: func() error {
+			tt.mu.Lock()
+			defer tt.mu.Unlock()
+			tt.runs++
+
+			return nil
+		},
+	}
+}
+
+func (tt *testTarget) count() int {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+
+	return tt.runs
+}
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+
+	web := &testTarget{dir: t.TempDir()}
+	db := &testTarget{dir: t.TempDir()}
+
+	write := func(path, content string) {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(filepath.Join(web.dir, "web.yaml"), "kind: Service\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var log bytes.Buffer
+
+	done := make(chan error)
+
+	go func() {
+		done <- Watch(ctx, &log, 50*time.Millisecond, web.target("web"), db.target("db"))
+	}()
+
+	// wait for the initial runs and for the changes to settle between steps
+	settle := func() { time.Sleep(300 * time.Millisecond) }
+
+	settle()
+	assert.Equal(t, 1, web.count(), "ensure targets are run initially")
+	assert.Equal(t, 1, db.count(), "ensure targets are run initially")
+
+	for i := 0; i < 5; i++ {
+		write(filepath.Join(web.dir, "web.yaml"), "kind: Deployment\n")
+	}
+
+	settle()
+	assert.Equal(t, 2, web.count(), "ensure a burst of changes to an input regenerates its target once")
+	assert.Equal(t, 1, db.count(), "ensure targets which are not affected are not regenerated")
+
+	write(filepath.Join(db.dir, "db.yaml"), "kind: StatefulSet\n")
+
+	settle()
+	assert.Equal(t, 2, db.count(), "ensure new files matching a glob regenerate the target")
+
+	write(filepath.Join(db.dir, "db.txt"), "unrelated")
+
+	settle()
+	assert.Equal(t, 2, db.count(), "ensure new files which are not inputs are ignored")
+
+	cancel()
+	assert.NoError(t, <-done)
+}
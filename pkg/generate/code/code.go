@@ -5,23 +5,33 @@ package code
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"go/format"
+	"io"
+	"regexp"
+	"strconv"
 	"strings"
 	"text/template"
 
 	"github.com/Masterminds/sprig/v3"
 	ghodss_yaml "github.com/ghodss/yaml"
-	"github.com/iancoleman/strcase"
-	"github.com/nukleros/gener8s/internal/options"
+	"github.com/nukleros/gener8s/pkg/generate/naming"
 	"github.com/nukleros/gener8s/pkg/manifests"
 	"gopkg.in/yaml.v3"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
-var ErrTooManyValues = errors.New("only one value struct is allowed")
+var (
+	ErrTooManyValues = errors.New("only one value struct is allowed")
+	ErrObjectCode    = errors.New("invalid go code generated for the object as a whole")
+)
+
+// templateErrorPosition matches the position reported by the errors from
+// templating yaml content, along with the remainder of the error message.
+var templateErrorPosition = regexp.MustCompile(`(?s)^template: [^:]*:(\d+)(?::(\d+))?: (.*)$`)
 
 type element struct {
 	Type        string
@@ -32,23 +42,43 @@ type element struct {
 	HeadComment string
 	FootComment string
 	Elements    elements
+	Ref         *reference
+	KeyRef      *reference
+	Flow        *flow
+
+	// Line and Column are the position of the value of the element within the
+	// yaml content.
+	Line   int
+	Column int
 }
 
 type object struct {
-	VarName  string
-	Elements elements
-	Source   string
+	VarName    string
+	Elements   elements
+	Source     string
+	Variables  []variable
+	Statements string
+	Spec       *spec
+}
+
+// generated represents generated go source code for an object along with the
+// imports the source code requires, and the parameters of a constructor.
+type generated struct {
+	Source     string
+	Imports    []string
+	Parameters []Parameter
 }
 
 type elements []element
 
 func (e *elements) UnmarshalYAML(value *yaml.Node) error {
-	e.decodeElements(0, value)
-
-	return nil
+	return e.decodeElements(nil, 0, value)
 }
 
-func (e *elements) decodeElements(factor int, value ...*yaml.Node) {
+// decodeElements decodes the elements of yaml nodes, which are pairs of keys and
+// values when the factor is 1.  Values with custom tags are decoded by their
+// handler from the registry of tags, or from the default registry.
+func (e *elements) decodeElements(tags *Registry, factor int, value ...*yaml.Node) error {
 	for i := 0; i < len(value); i += 1 + factor {
 		headComment := strings.Split(value[i].HeadComment, "\n")
 		for j := range headComment {
@@ -69,13 +99,57 @@ func (e *elements) decodeElements(factor int, value ...*yaml.Node) {
 			LineComment: strings.TrimPrefix(value[i+factor].LineComment, "#"),
 			HeadComment: hc,
 			FootComment: fc,
+			Line:        value[i+factor].Line,
+			Column:      value[i+factor].Column,
+		}
+
+		if factor > 0 && isVarTag(value[i].ShortTag()) {
+			if err := elem.decodeKeyReference(value[i].ShortTag()); err != nil {
+				return positionError(value[i].Line, value[i].Column, err)
+			}
+
+			elem.KeyRef.Line, elem.KeyRef.Column = value[i].Line, value[i].Column
+		}
+
+		// values with custom tags are substituted as a whole by the expression
+		// generated by the handler of the tag
+		if handler, ok := tags.lookup(elem.Type); ok {
+			if err := elem.decodeCustomTag(handler, value[i+factor]); err != nil {
+				return elem.positionError(err)
+			}
+
+			*e = append(*e, elem)
+
+			continue
+		}
+
+		if isFlowTag(elem.Type) {
+			if err := elem.decodeFlow(value[i+factor]); err != nil {
+				return elem.positionError(err)
+			}
+		}
+
+		// collections tagged as a variable reference are substituted as a whole
+		// by the expression given with the tag
+		if isVarTag(elem.Type) && value[i+factor].Kind != yaml.ScalarNode && value[i+factor].Kind != yaml.AliasNode {
+			if err := elem.decodeSubtreeReference(value[i+factor].Kind); err != nil {
+				return elem.positionError(err)
+			}
+
+			*e = append(*e, elem)
+
+			continue
 		}
 
 		switch value[i+factor].Kind {
 		case yaml.DocumentNode:
-			e.decodeElements(0, value[i].Content...)
+			if err := e.decodeElements(tags, 0, value[i].Content...); err != nil {
+				return err
+			}
 		case yaml.SequenceNode:
-			elem.Elements.decodeElements(0, value[i+factor].Content...)
+			if err := elem.Elements.decodeElements(tags, 0, value[i+factor].Content...); err != nil {
+				return err
+			}
 
 			for i := range elem.Elements {
 				elem.Elements[i].IsSeq = true
@@ -83,77 +157,340 @@ func (e *elements) decodeElements(factor int, value ...*yaml.Node) {
 
 			*e = append(*e, elem)
 		case yaml.MappingNode:
-			elem.Elements.decodeElements(1, value[i+factor].Content...)
+			if err := elem.Elements.decodeElements(tags, 1, value[i+factor].Content...); err != nil {
+				return err
+			}
+
 			*e = append(*e, elem)
 		case yaml.ScalarNode:
 			elem.Value = value[i+factor].Value
+
+			if err := elem.decodeReference(); err != nil {
+				return elem.positionError(err)
+			}
+
 			*e = append(*e, elem)
 		case yaml.AliasNode:
 			elem.Type = value[i+factor].Alias.ShortTag()
 			elem.Value = value[i+factor].Alias.Value
 			elem.LineComment = strings.Trim(value[i+factor].Alias.LineComment, "#")
-			elem.Elements.decodeElements(1, value[i+factor].Alias.Content...)
+
+			if err := elem.Elements.decodeElements(tags, 1, value[i+factor].Alias.Content...); err != nil {
+				return err
+			}
+
+			if err := elem.decodeReference(); err != nil {
+				return elem.positionError(err)
+			}
 
 			*e = append(*e, elem)
 		}
 	}
+
+	return nil
+}
+
+// templateError returns an error from templating yaml content at the position
+// within the content which it reports.
+func templateError(message string, err error) error {
+	match := templateErrorPosition.FindStringSubmatch(err.Error())
+	if match == nil {
+		return fmt.Errorf("%s, %w", message, err)
+	}
+
+	line, _ := strconv.Atoi(match[1])
+
+	column := 1
+	if match[2] != "" {
+		column, _ = strconv.Atoi(match[2])
+	}
+
+	return &manifests.PositionError{Line: line, Column: column, Err: fmt.Errorf("%s, %s", message, match[3])}
+}
+
+// positionError returns an error at the position of the element within the yaml
+// content.
+func (elem *element) positionError(err error) error {
+	return positionError(elem.Line, elem.Column, err)
+}
+
+// positionError returns an error at a position within the yaml content, unless
+// the error already has a position.
+func positionError(line, column int, err error) error {
+	var positionErr *manifests.PositionError
+	if errors.As(err, &positionErr) {
+		return err
+	}
+
+	return &manifests.PositionError{Line: line, Column: column, Err: err}
+}
+
+// decodeReference decodes the variable reference for a scalar element tagged
+// with the !!var tag.
+func (elem *element) decodeReference() error {
+	if !isVarTag(elem.Type) {
+		return nil
+	}
+
+	ref, err := parseReference(elem.Type, elem.Value)
+	if err != nil {
+		return err
+	}
+
+	value, err := ref.Value()
+	if err != nil {
+		return err
+	}
+
+	elem.Type = varTag
+	elem.Ref = ref
+	elem.Value = value
+
+	return nil
+}
+
+// decodeKeyReference decodes the variable reference for the key of an element
+// when the key is tagged with the !!var tag.  Keys of unstructured objects are
+// always strings, so keys of any other type are formatted with fmt.Sprint.
+func (elem *element) decodeKeyReference(tag string) error {
+	ref, err := parseReference(tag, elem.Key)
+	if err != nil {
+		return err
+	}
+
+	elem.KeyRef = ref
+	elem.Key = ref.Expr
+
+	if ref.Type != "" && ref.Type != "string" {
+		elem.Key = fmt.Sprintf("fmt.Sprint(%s)", ref.Expr)
+		ref.Imports = append(ref.Imports, "fmt")
+	}
+
+	return nil
+}
+
+// decodeSubtreeReference decodes the variable reference for a mapping or
+// sequence element which is tagged with the !!var tag, in the form of
+// !!var=<expression>.  As on scalars, a colon after the tag introduces a type,
+// which is instead implied by the kind of node, as map[string]interface{} or
+// []interface{} respectively.
+func (elem *element) decodeSubtreeReference(kind yaml.Kind) error {
+	if !strings.HasPrefix(elem.Type, varTag+"=") {
+		return fmt.Errorf("%w; the expression of a mapping or sequence follows the tag after =, as in %s=resources, found tag %s",
+			ErrInvalidVariableReference, varTag, elem.Type)
+	}
+
+	expr := strings.TrimPrefix(elem.Type, varTag+"=")
+
+	typeName := "map[string]interface{}"
+	if kind == yaml.SequenceNode {
+		typeName = "[]interface{}"
+	}
+
+	ref, err := parseReference(varTag, expr)
+	if err != nil {
+		return err
+	}
+
+	ref.Type = typeName
+
+	value, err := ref.Value()
+	if err != nil {
+		return err
+	}
+
+	elem.Type = varTag
+	elem.Ref = ref
+	elem.Value = value
+
+	return nil
+}
+
+// int64Values converts all integer values for a set of elements into int64
+// values, which is the integer type stored by unstructured objects.
+func (e elements) int64Values() {
+	for i := range e {
+		if e[i].Type == "!!int" {
+			e[i].Value = fmt.Sprintf("int64(%s)", e[i].Value)
+		}
+
+		e[i].Elements.int64Values()
+	}
 }
 
 // GenerateForManifests generates code for a set of manifest objects.
-func GenerateForManifests(manifests *manifests.Manifests, options *options.RBACOptions) (string, error) {
+//
+// Deprecated: GenerateForManifests has never generated any code.  Use
+// GenerateCode, or the Generator of the generate package, instead.
+func GenerateForManifests(manifests *manifests.Manifests, objectOptions *ObjectOptions) (string, error) {
 	return "", nil
 }
 
 // Generate generates unstructured go types for resources defined in yaml
-// manifests.
+// manifests.  The Generator of the generate package supersedes it, with options
+// rather than a variadic value and structured results.
 func Generate(resourceYaml []byte, varName string, values ...interface{}) (string, error) {
+	return generateSource(resourceYaml, varName, false, values...)
+}
+
+// GenerateFunc generates a constructor function, named funcName, which returns
+// the unstructured go type for a resource defined in a yaml manifest.  Typed
+// variable references (e.g. !!var:int32 replicas) are declared as parameters of
+// the generated function.
+func GenerateFunc(resourceYaml []byte, funcName string, values ...interface{}) (string, error) {
+	return generateSource(resourceYaml, funcName, true, values...)
+}
+
+// generateSource generates go source code for a single resource, including the
+// declaration of any imports it requires.
+func generateSource(resourceYaml []byte, name string, constructor bool, values ...interface{}) (string, error) {
+	objCode, err := generate(resourceYaml, name, constructor, nil, values...)
+	if err != nil {
+		return "", err
+	}
+
+	if len(objCode.Imports) == 0 {
+		return objCode.Source, nil
+	}
+
+	source, err := format.Source([]byte(importDecl(objCode.Imports) + "\n" + objCode.Source))
+	if err != nil {
+		return "", positionError(1, 1, fmt.Errorf("%w; %s, unable to format file", err, ErrObjectCode))
+	}
+
+	return string(source), nil
+}
+
+// generate generates the go source code for a single resource as either a
+// variable or a constructor function, where values with custom tags are
+// generated by the handlers of the registry of tags.
+func generate(resourceYaml []byte, name string, constructor bool, tags *Registry, values ...interface{}) (*generated, error) {
 	if len(values) > 1 {
-		return "", ErrTooManyValues
+		return nil, ErrTooManyValues
 	} else if len(values) == 1 {
 		yamlTemplate, err := template.New("yamlFile").Parse(string(resourceYaml))
 		if err != nil {
-			return "", fmt.Errorf("unable to parse template in yaml file, %w", err)
+			return nil, templateError("unable to parse template in yaml file", err)
 		}
 
 		var yamlBuf bytes.Buffer
 
 		if err := yamlTemplate.Execute(&yamlBuf, values[0]); err != nil {
-			return "", fmt.Errorf("unable to resolve templating in yaml file, %w", err)
+			return nil, templateError("unable to resolve templating in yaml file", err)
 		}
 
 		resourceYaml = yamlBuf.Bytes()
 	}
 
+	var document yaml.Node
+
+	if err := yaml.Unmarshal(resourceYaml, &document); err != nil {
+		return nil, manifests.YAMLError(fmt.Errorf("unable to unmarshal input yaml, %w", err))
+	}
+
 	unstructuredObj := elements{}
 
-	if err := yaml.Unmarshal(resourceYaml, &unstructuredObj); err != nil {
-		return "", fmt.Errorf("unable to unmarshal input yaml, %w", err)
+	if err := unstructuredObj.decodeElements(tags, 0, document.Content...); err != nil {
+		return nil, err
 	}
 
 	obj := object{
-		VarName:  varName,
+		VarName:  name,
 		Elements: unstructuredObj[0].Elements,
 		Source:   string(resourceYaml),
 	}
 
-	t, err := template.New("objectTemplate").Funcs(funcMap()).Parse(objTemplate)
-	if err != nil {
-		return "", fmt.Errorf("unable to parse template, %w", err)
+	// workload markers are only used to set fields of the spec which is passed
+	// to a constructor function, otherwise they are retained as comments
+	if constructor {
+		var fields []specField
+
+		if err := obj.Elements.markers(&fields); err != nil {
+			return nil, err
+		}
+
+		obj.Spec = newSpec(name, fields)
+	}
+
+	refs := unstructuredObj.references()
+	objTemplateName := "objectTemplate"
+
+	if err := validateReferences(refs); err != nil {
+		return nil, err
+	}
+
+	if constructor {
+		vars, err := variables(refs)
+		if err != nil {
+			return nil, err
+		}
+
+		obj.Variables = vars
+		obj.Elements.int64Values()
+		objTemplateName = "funcTemplate"
+
+		if obj.Elements.dynamic() {
+			if obj.Statements, err = flowStatements(templates, obj.Elements, vars); err != nil {
+				return nil, err
+			}
+
+			obj.Elements = obj.Elements.static()
+		}
+	} else if elem := obj.Elements.firstFlow(); elem != nil {
+		return nil, elem.positionError(ErrFlowRequiresConstructor)
 	}
 
 	var buf bytes.Buffer
 
-	err = t.Execute(&buf, obj)
-	if err != nil {
-		return "", fmt.Errorf("unable to generate go code, %w", err)
+	// errors in the generated go code which are not caught by validating the
+	// references cannot be traced back to an element, so are reported at the
+	// start of the object as errors of the object as a whole
+	if err := templates.ExecuteTemplate(&buf, objTemplateName, obj); err != nil {
+		return nil, positionError(1, 1, fmt.Errorf("%w; %s, unable to generate go code", err, ErrObjectCode))
 	}
 
 	objSource, err := format.Source(buf.Bytes())
 	if err != nil {
-		return "", fmt.Errorf("unable to format file, %w", err)
+		return nil, positionError(1, 1, fmt.Errorf("%w; %s, unable to format file", err, ErrObjectCode))
+	}
+
+	objImports, err := imports(refs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &generated{Source: string(objSource), Imports: objImports, Parameters: obj.parameters()}, nil
+}
+
+// parameters returns the parameters of the constructor function of an object, in
+// the order in which they are declared.
+func (obj *object) parameters() []Parameter {
+	var parameters []Parameter
+
+	if obj.Spec != nil {
+		parameters = append(parameters, Parameter{Name: specName, Type: "*" + obj.Spec.TypeName})
 	}
 
-	return string(objSource), nil
+	for _, v := range obj.Variables {
+		parameters = append(parameters, Parameter(v))
+	}
+
+	return parameters
+}
+
+// importDecl returns an import declaration for a set of imports.
+func importDecl(imports []string) string {
+	if len(imports) == 0 {
+		return ""
+	}
+
+	decl := "import (\n"
+
+	for _, imp := range imports {
+		decl += "\t" + ImportSpec(imp) + "\n"
+	}
+
+	return decl + ")\n"
 }
 
 func escape(str string) string {
@@ -178,13 +515,85 @@ func escape(str string) string {
 	return `"` + str + `"`
 }
 
+// key returns the map key of an element as it is written in go code.
+func key(elem element) string {
+	if elem.KeyRef != nil {
+		return elem.Key
+	}
+
+	return `"` + elem.Key + `"`
+}
+
+// templates are the templates of the generated go code for an object, which are
+// parsed once and are safe for concurrent use.
+var templates = parseTemplates()
+
+// parseTemplates parses the templates of the generated go code for an object.
+func parseTemplates() *template.Template {
+	t := template.Must(template.New("objectTemplate").Funcs(funcMap()).Parse(objTemplate))
+	template.Must(t.New("funcTemplate").Parse(funcTemplate))
+
+	return t
+}
+
 func funcMap() template.FuncMap {
 	f := sprig.TxtFuncMap()
 	f["escape"] = escape
+	f["key"] = key
 
 	return f
 }
 
+const funcTemplate = `
+{{- with .Spec }}
+// {{ .TypeName }} represents the configurable fields of the object.
+type {{ .TypeName }} struct {
+	{{- range .Fields }}
+	{{- if .Description }}
+	// {{ .Description }}
+	{{- end }}
+	{{- if .Default }}
+	// +kubebuilder:default={{ .Default }}
+	{{- end }}
+	{{ .Name }} {{ .Type }} ` + "`" + `json:"{{ .JSONName }},omitempty"` + "`" + `
+	{{- end }}
+}
+
+// {{ .DefaultFunc }} returns the configurable fields of the object set to their default values.
+func {{ .DefaultFunc }}() *{{ .TypeName }} {
+	return &{{ .TypeName }}{
+		{{- range .Fields }}
+		{{- if .Default }}
+		{{ .Name }}: {{ .Default }},
+		{{- end }}
+		{{- end }}
+	}
+}
+{{ end }}
+func {{ .VarName }}(
+	{{- if .Spec }}spec *{{ .Spec.TypeName }}{{ if .Variables }}, {{ end }}{{ end }}
+	{{- range $i, $v := .Variables }}{{ if $i }}, {{ end }}{{ $v.Name }} {{ $v.Type }}{{ end -}}
+) *unstructured.Unstructured {
+	{{- if .Statements }}
+	object := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			{{- template "element" .Elements }}
+		},
+	}
+
+	{{ .Statements }}
+
+	return object
+	{{- else }}
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			{{- template "element" .Elements }}
+		},
+	}
+	{{- end }}
+}
+`
+
 const objTemplate = `
 var {{ .VarName }} = &unstructured.Unstructured{
 	Object: map[string]interface{}{
@@ -199,38 +608,42 @@ var {{ .VarName }} = &unstructured.Unstructured{
 		{{- end }}
 		{{- if eq .Type "!!null" }}
 			{{- if ne .IsSeq true }}
-				"{{ .Key }}": nil,
+				{{ key . }}: nil,
 			{{- else }}
 				nil,
 			{{- end }}
 		{{- else if  or (eq .Type "!!bool") (eq .Type "!!int") }}
 			{{- if ne .IsSeq true }}
-				"{{ .Key }}": {{ .Value -}},  {{ if .LineComment }}// {{ .LineComment }}{{ end }}
+				{{ key . }}: {{ .Value -}},  {{ if .LineComment }}// {{ .LineComment }}{{ end }}
 			{{- else }}
 				{{ .Value -}},  {{ if .LineComment }}// {{ .LineComment }}{{ end }}
 			{{- end }}
 		{{- else if eq .Type "!!str" }}
 			{{- if ne .IsSeq true }}
-				"{{ .Key }}": {{ escape .Value -}},  {{ if .LineComment }}// {{ .LineComment }}{{ end }}
+				{{ key . }}: {{ escape .Value -}},  {{ if .LineComment }}// {{ .LineComment }}{{ end }}
 			{{- else }}
 				{{ escape .Value -}},  {{ if .LineComment }}// {{ .LineComment }}{{ end }}
 			{{- end }}
 		{{- else if eq .Type "!!var" }}
 			{{- if ne .IsSeq true }}
-				"{{ .Key }}": {{ .Value -}},  {{ if .LineComment }}// {{ .LineComment }}{{ end }}
+				{{ key . }}: {{ .Value -}},  {{ if .LineComment }}// {{ .LineComment }}{{ end }}
 			{{- else }}
 				{{ .Value -}},  {{ if .LineComment }}// {{ .LineComment }}{{ end }}
 			{{- end }}
 		{{- else if eq .Type "!!map" }}
 			{{- if ne .IsSeq true }}
-				"{{ .Key }}": map[string]interface{}{  {{ if .LineComment }}// {{ .LineComment }}{{ end }}
+				{{ key . }}: map[string]interface{}{  {{ if .LineComment }}// {{ .LineComment }}{{ end }}
 			{{- else }}
 				map[string]interface{}{ {{ if .LineComment }}// {{ .LineComment }}{{ end }}
 			{{- end }}
 				{{- template "element" .Elements }}
 			},
 		{{- else if eq .Type "!!seq" }}
-			"{{ .Key }}": []interface{}{
+			{{- if ne .IsSeq true }}
+				{{ key . }}: []interface{}{  {{ if .LineComment }}// {{ .LineComment }}{{ end }}
+			{{- else }}
+				[]interface{}{ {{ if .LineComment }}// {{ .LineComment }}{{ end }}
+			{{- end }}
 				{{- template "element" .Elements }}
 			},
 		{{- end }}
@@ -241,75 +654,225 @@ var {{ .VarName }} = &unstructured.Unstructured{
 {{- end }}
 `
 
+// UnstructuredImport is the import path of the unstructured package, which the
+// go source code of every object requires, and which is not included in the
+// imports of a source.
+const UnstructuredImport = "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+// Source represents the generated go source code for a set of objects, along
+// with the import paths it requires.
+type Source struct {
+	Code    string
+	Imports []string
+}
+
 // GenerateCode will return the stdout form of unstructured go code, given a set of input
 // manifests, in go struct format.
-func GenerateCode(files *manifests.Manifests, options *options.RBACOptions, values map[string]interface{}) (string, error) {
-	var goString string
-
-	manifestData, err := GenerateYAML(files)
+func GenerateCode(files *manifests.Manifests, objectOptions *ObjectOptions) (string, error) {
+	source, err := GenerateSource(files, objectOptions)
 	if err != nil {
-		return goString, fmt.Errorf("%w - error generating yaml", err)
+		return source.Code, err
 	}
 
-	manifest := &manifests.Manifest{Content: []byte(manifestData)}
+	return source.String(), nil
+}
 
-	extractedManifests := manifest.ExtractManifests()
+// GenerateSource returns the unstructured go code for a set of input manifests,
+// without the declaration of the imports it requires.  Objects are named with
+// the namer of the options, after their kind and name by default, unless a name
+// is given, which names a single object, or prefixes the names of multiple
+// objects.  GenerateObjects generates the same objects with a context.
+func GenerateSource(files *manifests.Manifests, objectOptions *ObjectOptions) (*Source, error) {
+	var code strings.Builder
 
-	for _, resource := range extractedManifests {
-		resource = strings.TrimSpace(resource)
-		if resource == "" {
-			continue
-		}
+	imports, err := WriteObjects(context.Background(), &code, files, objectOptions)
 
-		jsonManifest, err := ghodss_yaml.YAMLToJSON([]byte(resource))
-		if err != nil {
-			return "", fmt.Errorf("failed to convert YAML to JSON: %v", err)
-		}
+	return &Source{Code: code.String(), Imports: imports}, err
+}
 
-		// Create an unstructured object from the JSON representation.
-		unstructuredObj := &unstructured.Unstructured{}
-		if err := json.Unmarshal(jsonManifest, unstructuredObj); err != nil {
-			return "", fmt.Errorf("failed to unmarshal JSON into unstructured object: %v", err)
-		}
+// Join returns the go source code for a set of objects, in order, along with the
+// imports they require.
+func Join(objects []*Object) *Source {
+	var code strings.Builder
 
-		kind := unstructuredObj.GetKind()
-		name := strcase.ToCamel(unstructuredObj.GetName())
-		variableName := strcase.ToLowerCamel(kind + name)
+	var imports []string
 
-		asCode, err := Generate([]byte(resource), variableName, values)
-		if err != nil {
-			return goString, fmt.Errorf("%w - error generating code for yaml", err)
-		}
+	for i, object := range objects {
+		imports = append(imports, object.Imports...)
 
-		if goString == "" {
-			goString = fmt.Sprintf("%s\n\n", asCode)
-		} else {
-			goString = fmt.Sprintf("%s%s\n", goString, asCode)
-		}
+		// writing to a strings.Builder does not fail
+		_ = writeObject(&code, object, i == 0)
+	}
+
+	if len(imports) > 0 {
+		imports = uniqueSorted(imports)
+	}
+
+	return &Source{Code: code.String(), Imports: imports}
+}
+
+// Append appends the go source code of another source, which follows the code
+// of the source as the code of an object does, along with the imports it
+// requires.
+func (source *Source) Append(other *Source) {
+	source.Code += other.Code
+
+	if len(other.Imports) > 0 {
+		source.Imports = uniqueSorted(append(source.Imports, other.Imports...))
+	}
+}
+
+// String returns the go source code preceded by the declaration of the imports
+// it requires, if any.
+func (source *Source) String() string {
+	if len(source.Imports) > 0 {
+		return fmt.Sprintf("%s\n%s", importDecl(source.Imports), source.Code)
 	}
 
-	return goString, nil
+	return source.Code
+}
+
+// Object represents the generated go source code for a single object, along
+// with the object it was generated from.
+type Object struct {
+	Source
+
+	// Name is the name of the variable, or constructor function, of the object.
+	Name string
+
+	// Resource is the object the source code was generated from, along with its
+	// position within its manifest file.
+	Resource *manifests.Object
+
+	// Object is the unstructured form of the object.
+	Object *unstructured.Unstructured
+
+	// Constructor determines if the source code is a constructor function rather
+	// than a variable.
+	Constructor bool
+
+	// Parameters are the parameters of the constructor function of the object,
+	// in order, if any.
+	Parameters []Parameter
+}
+
+// Parameter represents a parameter of a generated constructor function.
+type Parameter struct {
+	Name string
+	Type string
+}
+
+// ObjectOptions represents the options for generating the go source code of
+// objects.
+type ObjectOptions struct {
+	// Values resolve the templating in the manifests.
+	Values map[string]interface{}
+
+	// Name names a single object, or prefixes the names of multiple objects,
+	// rather than their kind and name.
+	Name string
+
+	// Constructor determines if constructor functions are generated rather than
+	// variables.
+	Constructor bool
+
+	// Tags are the handlers of custom tags, in addition to those of the default
+	// registry.
+	Tags *Registry
+
+	// Workers is the maximum number of objects which are generated concurrently,
+	// which defaults to the number of CPUs which may run go code at once.
+	Workers int
+
+	// Namer names the objects which are not named by Name, and ensures that all
+	// names are unique.  A namer may be shared by several calls which generate
+	// objects into the same package.  When nil, objects are named with the
+	// default naming pattern.
+	Namer *naming.Namer
+}
+
+// GenerateObjects returns the unstructured go code for each object of a set of
+// input manifests, in order, as either a variable or a constructor function.
+// Objects are named as with GenerateSource, and are generated concurrently as
+// with StreamObjects.  Generation stops when the context is done, returning the
+// objects generated so far along with the error of the context.
+func GenerateObjects(ctx context.Context, files *manifests.Manifests, objectOptions *ObjectOptions) ([]*Object, error) {
+	var objects []*Object
+
+	err := StreamObjects(ctx, files, objectOptions, func(object *Object) error {
+		objects = append(objects, object)
+
+		return nil
+	})
+
+	return objects, err
+}
+
+// decodeObject returns the unstructured form of an object.
+func decodeObject(resource *manifests.Object) (*unstructured.Unstructured, error) {
+	jsonManifest, err := ghodss_yaml.YAMLToJSON([]byte(resource.Content))
+	if err != nil {
+		return nil, resource.Wrap(fmt.Errorf("failed to convert YAML to JSON: %w", err))
+	}
+
+	// Create an unstructured object from the JSON representation.
+	unstructuredObj := &unstructured.Unstructured{}
+	if err := json.Unmarshal(jsonManifest, unstructuredObj); err != nil {
+		return nil, resource.Wrap(fmt.Errorf("failed to unmarshal JSON into unstructured object: %w", err))
+	}
+
+	return unstructuredObj, nil
+}
+
+// generateObject returns the unstructured go code for a single named object.
+func generateObject(named *Object, objectOptions *ObjectOptions) (*Object, error) {
+	asCode, err := generate(
+		[]byte(named.Resource.Content),
+		named.Name,
+		objectOptions.Constructor,
+		objectOptions.Tags,
+		objectOptions.Values,
+	)
+	if err != nil {
+		return nil, named.Resource.Wrap(err)
+	}
+
+	named.Source = Source{Code: asCode.Source, Imports: asCode.Imports}
+	named.Constructor = objectOptions.Constructor
+	named.Parameters = asCode.Parameters
+
+	return named, nil
 }
 
 // GenerateYAML will return the stdout form of unstructured objects in YAML format given a set of input manifest.
 func GenerateYAML(files *manifests.Manifests) (string, error) {
+	var resources strings.Builder
+
+	if err := WriteYAML(&resources, files); err != nil {
+		return "", err
+	}
 
-	var resources []string
+	return resources.String(), nil
+}
+
+// WriteYAML writes the objects of a set of input manifests to a writer in YAML
+// format, as a single stream of documents.
+func WriteYAML(w io.Writer, files *manifests.Manifests) error {
+	var written int
 
 	for _, manifest := range *files {
-		resources = append(resources, manifest.ExtractManifests()...)
-	}
+		for _, resource := range manifest.ExtractManifests() {
+			if written > 0 {
+				resource = "---\n" + resource
+			}
 
-	var resourceString string
-	for i, resource := range resources {
+			if _, err := io.WriteString(w, resource); err != nil {
+				return fmt.Errorf("%w; unable to write yaml", err)
+			}
 
-		if i == 0 {
-			resourceString = resource
-		} else {
-			resourceString = fmt.Sprintf("%s---\n%s", resourceString, resource)
+			written++
 		}
 	}
 
-	return resourceString, nil
-
+	return nil
 }
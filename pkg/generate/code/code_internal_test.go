@@ -0,0 +1,240 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package code
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+
+	"github.com/nukleros/gener8s/pkg/manifests"
+)
+
+func Test_elements_decodeElements(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		yaml    string
+		want    elements
+		wantErr string
+	}{
+		{
+			name: "ensure variable reference on a mapping key is decoded",
+			yaml: "!!var:github.com/acme/api.LabelName teamKey: platform",
+			want: elements{
+				{
+					Type:   "!!str",
+					Key:    "fmt.Sprint(teamKey)",
+					Value:  "platform",
+					KeyRef: &reference{Expr: "teamKey", Type: "api.LabelName", Imports: []string{"github.com/acme/api", "fmt"}, Line: 1, Column: 1},
+					Line:   1,
+					Column: 46,
+				},
+			},
+		},
+		{
+			name: "ensure variable reference on a mapping substitutes the subtree",
+			yaml: "resources: !!var=containerResources\n  limits:\n    cpu: 1",
+			want: elements{
+				{
+					Type:   "!!var",
+					Key:    "resources",
+					Value:  "containerResources",
+					Ref:    &reference{Expr: "containerResources", Type: "map[string]interface{}"},
+					Line:   1,
+					Column: 12,
+				},
+			},
+		},
+		{
+			name: "ensure variable reference on a sequence substitutes the subtree",
+			yaml: "args: !!var=args []",
+			want: elements{
+				{
+					Type:   "!!var",
+					Key:    "args",
+					Value:  "args",
+					Ref:    &reference{Expr: "args", Type: "[]interface{}"},
+					Line:   1,
+					Column: 7,
+				},
+			},
+		},
+		{
+			name: "ensure variable reference on an integer mapping key is formatted as a string",
+			yaml: "!!var:int32 port: http",
+			want: elements{
+				{
+					Type:   "!!str",
+					Key:    "fmt.Sprint(port)",
+					Value:  "http",
+					KeyRef: &reference{Expr: "port", Type: "int32", Imports: []string{"fmt"}, Line: 1, Column: 1},
+					Line:   1,
+					Column: 19,
+				},
+			},
+		},
+		{
+			name:    "ensure variable reference on a mapping without an expression returns an error",
+			yaml:    "resources: !!var= {}",
+			wantErr: "1:12: invalid variable reference; missing expression for tag !!var",
+		},
+		{
+			name:    "ensure variable reference on a mapping with a type rather than an expression returns an error",
+			yaml:    "resources: !!var:containerResources {}",
+			wantErr: "1:12: invalid variable reference; the expression of a mapping or sequence follows the tag after =, as in !!var=resources, found tag !!var:containerResources",
+		},
+		{
+			name:    "ensure variable reference on a scalar with an expression in the tag returns an error",
+			yaml:    "replicas: !!var=replicas 1",
+			wantErr: "1:11: invalid variable reference; the expression of a scalar follows the tag after a space, as in !!var replicas, found tag !!var=replicas",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var node yaml.Node
+			if err := yaml.Unmarshal([]byte(tt.yaml), &node); err != nil {
+				t.Fatal(err)
+			}
+			got := elements{}
+			err := got.decodeElements(nil, 1, node.Content[0].Content...)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range got {
+				got[i].HeadComment, got[i].FootComment = "", ""
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_generate_positions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		yaml        string
+		constructor bool
+		values      []interface{}
+		want        string
+	}{
+		{
+			name:   "ensure templating errors are reported at their position",
+			yaml:   "kind: Service\nmetadata:\n  name: '{{ .Name.First }}'",
+			values: []interface{}{map[string]interface{}{"Name": "web"}},
+			want:   `3:17: unable to resolve templating in yaml file, executing "yamlFile" at <.Name.First>: can't evaluate field First in type interface {}`,
+		},
+		{
+			name: "ensure yaml errors are reported at their line",
+			yaml: "kind: Service\nspec: [\n",
+			want: "2:1: unable to unmarshal input yaml, yaml: line 2: did not find expected node content",
+		},
+		{
+			name: "ensure control flow without a constructor is reported at the tagged element",
+			yaml: "kind: Service\nspec:\n  tls: !!if:enableTLS\n    enabled: true",
+			want: "3:8: control flow tags require a constructor function to be generated",
+		},
+		{
+			name:        "ensure conflicting variable types are reported at the later reference",
+			yaml:        "kind: Service\nspec:\n  a: !!var:int32 n\n  b: !!var:string n",
+			constructor: true,
+			want:        "4:6: variable is referenced with conflicting types; n is referenced as both int32 and string",
+		},
+		{
+			name: "ensure invalid expressions are reported at the reference rather than the object",
+			yaml: "kind: Service\nspec:\n  name: !!var spec.(",
+			want: `3:9: invalid variable reference; "spec.(" is not a valid go expression, 1:7: expected type, found 'EOF'`,
+		},
+		{
+			name:        "ensure invalid types are reported at the reference rather than the object",
+			yaml:        "kind: Service\nspec:\n  ports: !!var:[]]int32 ports",
+			constructor: true,
+			want:        `3:10: invalid variable reference; "[]]int32" is not a valid go type, 1:3: expected type, found ']'`,
+		},
+		{
+			name:        "ensure references of types which unstructured objects cannot store are reported",
+			yaml:        "kind: Pod\nspec:\n  containers:\n    - !!var:(corev1)k8s.io/api/core/v1.Container sidecar",
+			constructor: true,
+			want: "4:7: invalid variable reference; type corev1.Container of sidecar is not a type which an " +
+				"unstructured object can store, reference a conversion of the value to string, int64, float64, bool, " +
+				"map[string]interface{} or []interface{} instead, such as string(sidecar) for a named string type " +
+				"or a map converted with runtime.DefaultUnstructuredConverter.ToUnstructured for a struct",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := generate([]byte(tt.yaml), "object", tt.constructor, nil, tt.values...)
+			assert.EqualError(t, err, tt.want)
+		})
+	}
+}
+
+func TestGenerateSource_names(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		content     string
+		objectName  string
+		constructor bool
+		want        []string
+	}{
+		{
+			name:    "ensure objects are named after their kind and name by default",
+			content: "kind: Service\nmetadata:\n  name: web-app\n",
+			want:    []string{"var serviceWebApp ="},
+		},
+		{
+			name:       "ensure a given name names a single object",
+			content:    "kind: Service\nmetadata:\n  name: web\n",
+			objectName: "frontend",
+			want:       []string{"var frontend ="},
+		},
+		{
+			name:        "ensure a given name names the constructor of a single object",
+			content:     "kind: Service\nmetadata:\n  name: web\n",
+			objectName:  "frontend",
+			constructor: true,
+			want:        []string{"func NewFrontend("},
+		},
+		{
+			name:       "ensure a given name prefixes the names of multiple objects",
+			content:    "kind: Service\nmetadata:\n  name: web\n---\nkind: ConfigMap\nmetadata:\n  name: web\n",
+			objectName: "frontend",
+			want:       []string{"var frontendServiceWeb =", "var frontendConfigMapWeb ="},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			files := manifests.Manifests{{Filename: "web.yaml", Content: []byte(tt.content)}}
+
+			got, err := GenerateSource(&files, &ObjectOptions{Name: tt.objectName, Constructor: tt.constructor})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, want := range tt.want {
+				assert.Contains(t, got.Code, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package code
+
+import (
+	"errors"
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/iancoleman/strcase"
+)
+
+var ErrInvalidCollection = errors.New("invalid collection")
+
+// OtherKinds is the entry of a kind order which places the kinds which are not
+// otherwise listed.
+const OtherKinds = "*"
+
+// teardownSuffix is the suffix of the name of the function which returns the
+// objects of a collection in teardown order.
+const teardownSuffix = "Teardown"
+
+// clientImport is the import path of the controller-runtime client package,
+// which declares the object interface returned by a collection.
+const clientImport = "sigs.k8s.io/controller-runtime/pkg/client"
+
+// DefaultKindOrder is the order in which objects are installed by kind, which
+// is that of helm, other than that admission webhooks are installed after all
+// other objects, so that they do not intercept the installation of the objects
+// they depend on.
+var DefaultKindOrder = []string{
+	"Namespace",
+	"NetworkPolicy",
+	"ResourceQuota",
+	"LimitRange",
+	"PodSecurityPolicy",
+	"PodDisruptionBudget",
+	"ServiceAccount",
+	"Secret",
+	"SecretList",
+	"ConfigMap",
+	"StorageClass",
+	"PersistentVolume",
+	"PersistentVolumeClaim",
+	"CustomResourceDefinition",
+	"ClusterRole",
+	"ClusterRoleList",
+	"ClusterRoleBinding",
+	"ClusterRoleBindingList",
+	"Role",
+	"RoleList",
+	"RoleBinding",
+	"RoleBindingList",
+	"Service",
+	"DaemonSet",
+	"Pod",
+	"ReplicationController",
+	"ReplicaSet",
+	"Deployment",
+	"HorizontalPodAutoscaler",
+	"StatefulSet",
+	"Job",
+	"CronJob",
+	"IngressClass",
+	"Ingress",
+	"APIService",
+	OtherKinds,
+	"MutatingWebhookConfiguration",
+	"ValidatingWebhookConfiguration",
+}
+
+// Collection represents a function which returns a set of generated objects as
+// a []client.Object in install order, sorted by kind, along with a function
+// which returns them in the reverse order for teardown.
+type Collection struct {
+	// Name is the name of the function which returns the objects in install
+	// order, which is suffixed with Teardown for the function which returns them
+	// in teardown order.
+	Name string
+
+	// KindOrder is the order in which objects are installed by kind, which
+	// defaults to DefaultKindOrder.  Kinds which are not listed are installed in
+	// place of OtherKinds, or after all listed kinds if it is not listed.
+	KindOrder []string
+
+	objects []*Object
+}
+
+// Add adds generated objects to the collection.
+func (collection *Collection) Add(objects ...*Object) {
+	collection.objects = append(collection.objects, objects...)
+}
+
+// Objects returns the objects of the collection in install order.  Objects of
+// the same kind, or of kinds with the same priority, retain the order in which
+// they were added.
+func (collection *Collection) Objects() []*Object {
+	kindOrder := collection.KindOrder
+	if len(kindOrder) == 0 {
+		kindOrder = DefaultKindOrder
+	}
+
+	priorities := map[string]int{}
+
+	for i, kind := range kindOrder {
+		if _, ok := priorities[kind]; !ok {
+			priorities[kind] = i
+		}
+	}
+
+	others, ok := priorities[OtherKinds]
+	if !ok {
+		others = len(kindOrder)
+	}
+
+	priority := func(object *Object) int {
+		if p, ok := priorities[object.Object.GetKind()]; ok {
+			return p
+		}
+
+		return others
+	}
+
+	sorted := make([]*Object, len(collection.objects))
+	copy(sorted, collection.objects)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return priority(sorted[i]) < priority(sorted[j])
+	})
+
+	return sorted
+}
+
+// Source returns the go source code of the functions of the collection, along
+// with the imports it requires.  The functions of a collection of constructor
+// functions take the parameters of all of the constructors, where the spec of
+// each constructor is named after its type.
+func (collection *Collection) Source() (*Source, error) {
+	if !token.IsIdentifier(collection.Name) {
+		return nil, fmt.Errorf("%w; %q is not a valid go identifier", ErrInvalidCollection, collection.Name)
+	}
+
+	objects := collection.Objects()
+
+	parameters, imports, err := collectionParameters(objects)
+	if err != nil {
+		return nil, err
+	}
+
+	elements := make([]string, len(objects))
+
+	for i, object := range objects {
+		elements[i] = object.Name
+
+		if object.Constructor {
+			arguments := make([]string, len(object.Parameters))
+			for j, parameter := range object.Parameters {
+				arguments[j] = argumentName(parameter)
+			}
+
+			elements[i] = fmt.Sprintf("%s(%s)", object.Name, strings.Join(arguments, ", "))
+		}
+	}
+
+	var declared []string
+	for _, parameter := range parameters {
+		declared = append(declared, parameter.Name+" "+parameter.Type)
+	}
+
+	var code strings.Builder
+
+	fmt.Fprintf(&code, "// %s returns the objects in install order.\n", collection.Name)
+	writeCollectionFunc(&code, collection.Name, declared, elements)
+
+	reversed := make([]string, len(elements))
+	for i, element := range elements {
+		reversed[len(elements)-1-i] = element
+	}
+
+	fmt.Fprintf(&code, "\n// %s%s returns the objects in teardown order, which is the reverse of their install order.\n",
+		collection.Name, teardownSuffix)
+	writeCollectionFunc(&code, collection.Name+teardownSuffix, declared, reversed)
+
+	formatted, err := format.Source([]byte(code.String()))
+	if err != nil {
+		return nil, fmt.Errorf("%w; unable to format collection %s", err, collection.Name)
+	}
+
+	return &Source{Code: string(formatted), Imports: uniqueSorted(append(imports, clientImport))}, nil
+}
+
+// writeCollectionFunc writes a function of a collection which returns a list of
+// objects.
+func writeCollectionFunc(code *strings.Builder, name string, parameters, elements []string) {
+	fmt.Fprintf(code, "func %s(%s) []client.Object {\n\treturn []client.Object{\n", name, strings.Join(parameters, ", "))
+
+	for _, element := range elements {
+		fmt.Fprintf(code, "\t\t%s,\n", element)
+	}
+
+	code.WriteString("\t}\n}\n")
+}
+
+// argumentName returns the name of the argument of a collection which is passed
+// as a parameter of a constructor function.  Specs are named after their type,
+// as each constructor has a spec of its own type.
+func argumentName(parameter Parameter) string {
+	if parameter.Name == specName {
+		return strcase.ToLowerCamel(strings.TrimPrefix(parameter.Type, "*"))
+	}
+
+	return parameter.Name
+}
+
+// collectionParameters returns the parameters of the functions of a collection,
+// which are the parameters of all of its constructor functions in the order in
+// which they are first declared, along with the imports their types require.
+func collectionParameters(objects []*Object) ([]Parameter, []string, error) {
+	var parameters []Parameter
+
+	var imports []string
+
+	declared := map[string]string{}
+
+	for _, object := range objects {
+		for _, parameter := range object.Parameters {
+			name := argumentName(parameter)
+
+			if existing, ok := declared[name]; ok {
+				if existing != parameter.Type {
+					return nil, nil, fmt.Errorf("%w; %s is a parameter of type both %s and %s",
+						ErrConflictingVariableType, name, existing, parameter.Type)
+				}
+
+				continue
+			}
+
+			declared[name] = parameter.Type
+			parameters = append(parameters, Parameter{Name: name, Type: parameter.Type})
+			imports = append(imports, typeImports(parameter.Type, object.Imports)...)
+		}
+	}
+
+	return parameters, imports, nil
+}
+
+// typeImports returns the imports, of a set of imports, which a type refers to.
+// Qualified types refer to the name of their import, which is the identifier
+// before the dot of the qualified type.
+func typeImports(typeName string, paths []string) []string {
+	qualifiers := map[string]bool{}
+
+	start := -1
+
+	for i, r := range typeName + " " {
+		switch {
+		case unicode.IsLetter(r) || r == '_' || (unicode.IsDigit(r) && start >= 0):
+			if start < 0 {
+				start = i
+			}
+		default:
+			// a selected identifier is not itself a qualifier
+			if start >= 0 && r == '.' && (start == 0 || typeName[start-1] != '.') {
+				qualifiers[typeName[start:i]] = true
+			}
+
+			start = -1
+		}
+	}
+
+	var imports []string
+
+	for _, path := range paths {
+		if qualifiers[importName(path)] {
+			imports = append(imports, path)
+		}
+	}
+
+	return imports
+}
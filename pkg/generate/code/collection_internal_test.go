@@ -0,0 +1,228 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package code
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// collectionObject returns a generated object of a kind for a collection.
+func collectionObject(kind, name string, parameters ...Parameter) *Object {
+	object := &unstructured.Unstructured{}
+	object.SetKind(kind)
+
+	return &Object{Name: name, Object: object, Constructor: len(parameters) > 0, Parameters: parameters}
+}
+
+func TestCollection_Objects(t *testing.T) {
+	t.Parallel()
+
+	objects := []*Object{
+		collectionObject("ValidatingWebhookConfiguration", "webhook"),
+		collectionObject("Widget", "widget"),
+		collectionObject("Deployment", "deployment"),
+		collectionObject("ServiceAccount", "serviceAccount"),
+		collectionObject("CustomResourceDefinition", "crd"),
+		collectionObject("Gadget", "gadget"),
+		collectionObject("Namespace", "namespace"),
+	}
+
+	tests := []struct {
+		name      string
+		kindOrder []string
+		want      []string
+	}{
+		{
+			name:      "ensure objects are sorted by the default kind order",
+			kindOrder: nil,
+			want:      []string{"namespace", "serviceAccount", "crd", "deployment", "widget", "gadget", "webhook"},
+		},
+		{
+			name:      "ensure kinds which are not listed are sorted in place of other kinds",
+			kindOrder: []string{"Namespace", "*", "Deployment"},
+			want:      []string{"namespace", "webhook", "widget", "serviceAccount", "crd", "gadget", "deployment"},
+		},
+		{
+			name:      "ensure kinds which are not listed are sorted last without other kinds",
+			kindOrder: []string{"Gadget", "Widget"},
+			want:      []string{"gadget", "widget", "webhook", "deployment", "serviceAccount", "crd", "namespace"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			collection := &Collection{Name: "Objects", KindOrder: tt.kindOrder}
+			collection.Add(objects...)
+
+			var got []string
+			for _, object := range collection.Objects() {
+				got = append(got, object.Name)
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCollection_Source(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		collection  string
+		objects     []*Object
+		want        string
+		wantImports []string
+		wantErr     error
+	}{
+		{
+			name:       "ensure variables are returned in install and teardown order",
+			collection: "Objects",
+			objects: []*Object{
+				collectionObject("Deployment", "deploymentWeb"),
+				collectionObject("Namespace", "namespaceShop"),
+			},
+			want: `// Objects returns the objects in install order.
+func Objects() []client.Object {
+	return []client.Object{
+		namespaceShop,
+		deploymentWeb,
+	}
+}
+
+// ObjectsTeardown returns the objects in teardown order, which is the reverse of their install order.
+func ObjectsTeardown() []client.Object {
+	return []client.Object{
+		deploymentWeb,
+		namespaceShop,
+	}
+}
+`,
+			wantImports: []string{clientImport},
+		},
+		{
+			name:       "ensure constructors are called with the parameters of all constructors",
+			collection: "objects",
+			objects: []*Object{
+				collectionObject("Deployment", "NewDeploymentWeb",
+					Parameter{Name: specName, Type: "*DeploymentWebSpec"},
+					Parameter{Name: "image", Type: "config.Image"},
+				),
+				collectionObject("Service", "NewServiceWeb", Parameter{Name: "image", Type: "config.Image"}),
+				{
+					Name:        "NewConfigMapWeb",
+					Object:      collectionObject("ConfigMap", "").Object,
+					Constructor: true,
+				},
+			},
+			want: `// objects returns the objects in install order.
+func objects(image config.Image, deploymentWebSpec *DeploymentWebSpec) []client.Object {
+	return []client.Object{
+		NewConfigMapWeb(),
+		NewServiceWeb(image),
+		NewDeploymentWeb(deploymentWebSpec, image),
+	}
+}
+
+// objectsTeardown returns the objects in teardown order, which is the reverse of their install order.
+func objectsTeardown(image config.Image, deploymentWebSpec *DeploymentWebSpec) []client.Object {
+	return []client.Object{
+		NewDeploymentWeb(deploymentWebSpec, image),
+		NewServiceWeb(image),
+		NewConfigMapWeb(),
+	}
+}
+`,
+			wantImports: []string{"github.com/acme/app/config", clientImport},
+		},
+		{
+			name:       "ensure parameters with conflicting types are rejected",
+			collection: "Objects",
+			objects: []*Object{
+				collectionObject("Deployment", "NewDeploymentWeb", Parameter{Name: "replicas", Type: "int32"}),
+				collectionObject("StatefulSet", "NewStatefulSetWeb", Parameter{Name: "replicas", Type: "int"}),
+			},
+			wantErr: ErrConflictingVariableType,
+		},
+		{
+			name:       "ensure names which are not go identifiers are rejected",
+			collection: "all-objects",
+			wantErr:    ErrInvalidCollection,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			for _, object := range tt.objects {
+				object.Imports = []string{"github.com/acme/app/config", "github.com/acme/app/names"}
+			}
+
+			collection := &Collection{Name: tt.collection}
+			collection.Add(tt.objects...)
+
+			got, err := collection.Source()
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, tt.want, got.Code)
+			assert.Equal(t, tt.wantImports, got.Imports)
+		})
+	}
+}
+
+func Test_typeImports(t *testing.T) {
+	t.Parallel()
+
+	paths := []string{"github.com/acme/app/config", "github.com/acme/myconfig", "api github.com/acme/app/api/v1"}
+
+	tests := []struct {
+		name     string
+		typeName string
+		want     []string
+	}{
+		{
+			name:     "ensure the import of a qualified type is returned",
+			typeName: "*config.Args",
+			want:     []string{"github.com/acme/app/config"},
+		},
+		{
+			name:     "ensure qualifiers ending with the name of an import are not matched",
+			typeName: "[]myconfig.Args",
+			want:     []string{"github.com/acme/myconfig"},
+		},
+		{
+			name:     "ensure imports are matched by their name",
+			typeName: "map[string]api.Port",
+			want:     []string{"api github.com/acme/app/api/v1"},
+		},
+		{
+			name:     "ensure unqualified types have no imports",
+			typeName: "configArgs",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, typeImports(tt.typeName, paths))
+		})
+	}
+}
@@ -0,0 +1,332 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package code
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/token"
+	"strings"
+	"text/template"
+
+	"github.com/iancoleman/strcase"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrInvalidFlowTag            = errors.New("invalid control flow tag")
+	ErrFlowRequiresConstructor   = errors.New("control flow tags require a constructor function to be generated")
+	ErrRangeRequiresSequenceNode = errors.New("range tag may only be used on a sequence")
+)
+
+const (
+	ifTag    = "!!if"
+	rangeTag = "!!range"
+
+	// objectName is the name of the object which is returned from a generated
+	// constructor function that contains control flow.
+	objectName = "object"
+)
+
+// flow represents the control flow for an element, set with either the !!if tag
+// (e.g. !!if:enableTLS) or the !!range tag (e.g. !!range:sidecar=sidecars).
+// Elements with control flow are set on the object with go statements rather
+// than as part of the static object literal.
+type flow struct {
+	Cond      *reference
+	RangeVar  string
+	RangeList *reference
+}
+
+// isFlowTag determines if a yaml tag is a control flow tag.
+func isFlowTag(tag string) bool {
+	for _, flowTag := range []string{ifTag, rangeTag} {
+		if tag == flowTag || strings.HasPrefix(tag, flowTag+":") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseFlow parses the control flow for a !!if or !!range tag.  The !!if tag is
+// in the form of !!if:<condition> and the !!range tag is in the form of
+// !!range:[<loop variable>=]<expression>[:<element type>].  A condition which is
+// a plain identifier is declared as a bool parameter and a range expression
+// which is a plain identifier is declared as a slice parameter when the element
+// type is given.  The expression follows the tag after a colon rather than a
+// space, as yaml reads anything after a space as the value of the node, which
+// leaves no room for the mapping or sequence the tag is set on.
+func parseFlow(tag string, kind yaml.Kind) (*flow, error) {
+	switch tag {
+	case ifTag:
+		return nil, fmt.Errorf("%w; the condition must follow the tag after a colon, as in %s:enableTLS", ErrInvalidFlowTag, ifTag)
+	case rangeTag:
+		return nil, fmt.Errorf("%w; the expression must follow the tag after a colon, as in %s:sidecars", ErrInvalidFlowTag, rangeTag)
+	}
+
+	if strings.HasPrefix(tag, ifTag+":") {
+		cond, err := parseReference(varTag+":bool", strings.TrimPrefix(tag, ifTag+":"))
+		if err != nil {
+			return nil, fmt.Errorf("%w; %s", ErrInvalidFlowTag, err)
+		}
+
+		return &flow{Cond: cond}, nil
+	}
+
+	if kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("%w; found tag %s", ErrRangeRequiresSequenceNode, tag)
+	}
+
+	f := &flow{}
+	clause := strings.TrimPrefix(tag, rangeTag+":")
+
+	if eq := strings.Index(clause, "="); eq >= 0 {
+		f.RangeVar = clause[:eq]
+		clause = clause[eq+1:]
+
+		if !token.IsIdentifier(f.RangeVar) {
+			return nil, fmt.Errorf("%w; invalid loop variable %q for tag %s", ErrInvalidFlowTag, f.RangeVar, tag)
+		}
+	}
+
+	refTag := varTag
+	if colon := strings.Index(clause, ":"); colon >= 0 {
+		refTag = fmt.Sprintf("%s:[]%s", varTag, clause[colon+1:])
+		clause = clause[:colon]
+	}
+
+	list, err := parseReference(refTag, clause)
+	if err != nil {
+		return nil, fmt.Errorf("%w; %s", ErrInvalidFlowTag, err)
+	}
+
+	f.RangeList = list
+
+	return f, nil
+}
+
+// decodeFlow decodes the control flow for an element with a control flow tag
+// and sets the type of the element to the type the node would have without it.
+func (elem *element) decodeFlow(node *yaml.Node) error {
+	f, err := parseFlow(elem.Type, node.Kind)
+	if err != nil {
+		return err
+	}
+
+	untagged := *node
+	untagged.Tag = ""
+
+	elem.Flow = f
+	elem.Type = untagged.ShortTag()
+
+	return nil
+}
+
+// dynamic determines if a set of elements contains any elements which require
+// control flow.
+func (e elements) dynamic() bool {
+	return e.firstFlow() != nil
+}
+
+// firstFlow returns the first element of a set of elements which requires
+// control flow, or nil if there is none.
+func (e elements) firstFlow() *element {
+	for i := range e {
+		if e[i].Flow != nil {
+			return &e[i]
+		}
+
+		if elem := e[i].Elements.firstFlow(); elem != nil {
+			return elem
+		}
+	}
+
+	return nil
+}
+
+// static returns a copy of a set of elements without any elements which are set
+// with control flow.  Sequences which contain elements that require control
+// flow are built entirely with control flow, to retain the order of their items.
+func (e elements) static() elements {
+	staticElements := elements{}
+
+	for _, elem := range e {
+		if elem.Flow != nil || (elem.Type == "!!seq" && elem.Elements.dynamic()) {
+			continue
+		}
+
+		elem.Elements = elem.Elements.static()
+		staticElements = append(staticElements, elem)
+	}
+
+	return staticElements
+}
+
+// flowWriter writes the go statements which set the elements of an object that
+// require control flow.
+type flowWriter struct {
+	tpl   *template.Template
+	buf   strings.Builder
+	names map[string]bool
+	err   error
+}
+
+// flowStatements returns the go statements which set the elements that require
+// control flow onto the object returned from a constructor function.
+func flowStatements(tpl *template.Template, e elements, vars []variable) (string, error) {
+	w := &flowWriter{
+		tpl:   tpl,
+		names: map[string]bool{objectName: true},
+	}
+
+	for _, v := range vars {
+		w.names[v.Name] = true
+	}
+
+	w.setFields(objectName+".Object", nil, e)
+
+	return w.buf.String(), w.err
+}
+
+// printf writes a formatted statement.
+func (w *flowWriter) printf(format string, a ...interface{}) {
+	fmt.Fprintf(&w.buf, format, a...)
+}
+
+// local returns a unique name for a local variable for the value of an element.
+func (w *flowWriter) local(key string) string {
+	base := strcase.ToLowerCamel(key)
+	if !token.IsIdentifier(base) || token.IsKeyword(base) {
+		base = "item"
+	}
+
+	name := base
+	for i := 2; w.names[name]; i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+
+	w.names[name] = true
+
+	return name
+}
+
+// setFields writes the statements which set the elements of a mapping that
+// require control flow, at the given path of fields within the root map.
+func (w *flowWriter) setFields(root string, path []string, e elements) {
+	for _, elem := range e {
+		fields := append(append([]string{}, path...), key(elem))
+
+		switch {
+		case elem.Flow != nil && elem.Flow.Cond != nil:
+			if elem.HeadComment != "" {
+				w.printf("%s\n", elem.HeadComment)
+			}
+
+			w.printf("if %s {\n", elem.Flow.Cond.Expr)
+			w.set(root, w.value(elem), fields)
+			w.printf("}\n")
+		case elem.Flow != nil || (elem.Type == "!!seq" && elem.Elements.dynamic()):
+			if elem.HeadComment != "" {
+				w.printf("%s\n", elem.HeadComment)
+			}
+
+			w.set(root, w.value(elem), fields)
+		case elem.Type == "!!map" && elem.Elements.dynamic():
+			w.setFields(root, fields, elem.Elements)
+
+			continue
+		default:
+			continue
+		}
+
+		// separate the statements for each top level element
+		if root == objectName+".Object" {
+			w.printf("\n")
+		}
+	}
+}
+
+// set writes the statement which sets a value at the given path of fields
+// within the root map.  The maps along the path are always part of the static
+// literal, so the value is assigned to its parent map directly rather than with
+// unstructured.SetNestedField, which deep copies the value again.
+func (w *flowWriter) set(root, value string, fields []string) {
+	parent := root
+	for _, field := range fields[:len(fields)-1] {
+		parent = fmt.Sprintf("%s[%s].(map[string]interface{})", parent, field)
+	}
+
+	w.printf("%s[%s] = %s\n", parent, fields[len(fields)-1], value)
+}
+
+// value writes any statements needed to build the value of an element and
+// returns the go expression for the value.
+func (w *flowWriter) value(elem element) string {
+	switch {
+	case elem.Type == "!!seq" && (elem.Flow != nil && elem.Flow.RangeList != nil || elem.Elements.dynamic()):
+		return w.slice(elem)
+	case elem.Type == "!!map" && elem.Elements.dynamic():
+		name := w.local(elem.Key)
+
+		w.printf("%s := %s\n", name, w.literal(elem))
+		w.setFields(name, nil, elem.Elements)
+
+		return name
+	}
+
+	return w.literal(elem)
+}
+
+// slice writes the statements which build a sequence item by item and returns
+// the name of the local variable holding the sequence.
+func (w *flowWriter) slice(elem element) string {
+	name := w.local(elem.Key)
+
+	w.printf("%s := []interface{}{}\n", name)
+
+	ranged := elem.Flow != nil && elem.Flow.RangeList != nil
+	if ranged {
+		if elem.Flow.RangeVar == "" {
+			w.printf("for range %s {\n", elem.Flow.RangeList.Expr)
+		} else {
+			w.names[elem.Flow.RangeVar] = true
+			w.printf("for _, %s := range %s {\n", elem.Flow.RangeVar, elem.Flow.RangeList.Expr)
+		}
+	}
+
+	for _, item := range elem.Elements {
+		if item.Flow != nil && item.Flow.Cond != nil {
+			w.printf("if %s {\n", item.Flow.Cond.Expr)
+			w.printf("%s = append(%s, %s)\n", name, name, w.value(item))
+			w.printf("}\n")
+
+			continue
+		}
+
+		w.printf("%s = append(%s, %s)\n", name, name, w.value(item))
+	}
+
+	if ranged {
+		w.printf("}\n")
+	}
+
+	return name
+}
+
+// literal returns the go literal for the static content of an element.
+func (w *flowWriter) literal(elem element) string {
+	elem.Elements = elem.Elements.static()
+	elem.IsSeq = true
+	elem.HeadComment, elem.LineComment, elem.FootComment = "", "", ""
+
+	var buf bytes.Buffer
+
+	if err := w.tpl.ExecuteTemplate(&buf, "element", elements{elem}); err != nil && w.err == nil {
+		w.err = fmt.Errorf("%w; %s, unable to generate go code", err, ErrObjectCode)
+	}
+
+	return strings.TrimSuffix(strings.TrimSpace(buf.String()), ",")
+}
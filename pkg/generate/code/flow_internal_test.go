@@ -0,0 +1,125 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package code
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func Test_parseFlow(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		tag  string
+		kind yaml.Kind
+	}
+
+	tests := []struct {
+		name    string
+		args    args
+		want    *flow
+		wantErr bool
+	}{
+		{
+			name: "ensure if tag declares a bool condition",
+			args: args{
+				tag:  "!!if:enableTLS",
+				kind: yaml.MappingNode,
+			},
+			want: &flow{
+				Cond: &reference{Expr: "enableTLS", Type: "bool"},
+			},
+		},
+		{
+			name: "ensure range tag with loop variable and element type is parsed",
+			args: args{
+				tag:  "!!range:sidecar=sidecars:github.com/acme/api.Sidecar",
+				kind: yaml.SequenceNode,
+			},
+			want: &flow{
+				RangeVar:  "sidecar",
+				RangeList: &reference{Expr: "sidecars", Type: "[]api.Sidecar", Imports: []string{"github.com/acme/api"}},
+			},
+		},
+		{
+			name: "ensure range tag without loop variable is parsed",
+			args: args{
+				tag:  "!!range:sidecars",
+				kind: yaml.SequenceNode,
+			},
+			want: &flow{
+				RangeList: &reference{Expr: "sidecars"},
+			},
+		},
+		{
+			name: "ensure if tag with the condition after a space returns an error",
+			args: args{
+				tag:  "!!if",
+				kind: yaml.ScalarNode,
+			},
+			wantErr: true,
+		},
+		{
+			name: "ensure range tag on a mapping returns an error",
+			args: args{
+				tag:  "!!range:sidecars",
+				kind: yaml.MappingNode,
+			},
+			wantErr: true,
+		},
+		{
+			name: "ensure range tag with invalid loop variable returns an error",
+			args: args{
+				tag:  "!!range:side-car=sidecars",
+				kind: yaml.SequenceNode,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := parseFlow(tt.args.tag, tt.args.kind)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseFlow() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_flowStatements(t *testing.T) {
+	t.Parallel()
+
+	content := `kind: Deployment
+spec:
+  tls: !!if:enableTLS
+    mode: !!var mode
+  containers: !!range:sidecars:string
+    - name: !!var:int32 port
+`
+
+	got, err := generate([]byte(content), "web", true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Contains(t, got.Source, `if enableTLS {
+		object.Object["spec"].(map[string]interface{})["tls"] = map[string]interface{}{
+			"mode": mode,
+		}
+	}`)
+	assert.Contains(t, got.Source, `containers = append(containers, map[string]interface{}{
+			"name": int64(port),
+		})`)
+	assert.Contains(t, got.Source, `object.Object["spec"].(map[string]interface{})["containers"] = containers`)
+	assert.NotContains(t, got.Source, "SetNestedField")
+}
@@ -0,0 +1,253 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package code
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+)
+
+var (
+	ErrInvalidMarker     = errors.New("invalid workload marker")
+	ErrConflictingMarker = errors.New("workload marker field is defined with conflicting options")
+)
+
+const (
+	workloadMarkerPrefix = "+workload:"
+
+	// specName is the name of the parameter of a generated constructor function
+	// which holds the fields set with workload markers.
+	specName = "spec"
+
+	defaultMarkerType = "string"
+)
+
+// marker represents a workload field marker, in the form of
+// +workload:<name>[:default=<value>][:type=<type>][:description=<text>], which is
+// set in the comments of a manifest field.
+type marker struct {
+	Name        string
+	Type        string
+	Default     string
+	Description string
+}
+
+// specField represents a field of the spec struct which is generated for the
+// workload markers of an object.
+type specField struct {
+	Name        string
+	JSONName    string
+	Type        string
+	Default     string
+	Description string
+}
+
+// spec represents the spec struct which is generated for the workload markers of
+// an object, along with a function returning the spec with its default values.
+type spec struct {
+	TypeName    string
+	DefaultFunc string
+	Fields      []specField
+}
+
+// parseMarker parses a workload marker from a comment, returning nil if the
+// comment does not contain a workload marker.
+func parseMarker(comment string) (*marker, error) {
+	comment = strings.TrimSpace(comment)
+	comment = strings.TrimSpace(strings.TrimLeft(comment, "#/"))
+
+	if !strings.HasPrefix(comment, workloadMarkerPrefix) {
+		return nil, nil
+	}
+
+	parts := splitMarker(strings.TrimPrefix(comment, workloadMarkerPrefix))
+
+	m := &marker{Name: parts[0], Type: defaultMarkerType}
+	if m.Name == "" {
+		return nil, fmt.Errorf("%w; missing field name in %s", ErrInvalidMarker, comment)
+	}
+
+	for _, part := range parts[1:] {
+		eq := strings.Index(part, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("%w; expected key=value but found %s in %s", ErrInvalidMarker, part, comment)
+		}
+
+		switch key, value := part[:eq], part[eq+1:]; key {
+		case "default":
+			m.Default = value
+		case "type":
+			m.Type = value
+		case "description":
+			m.Description = unquote(value)
+		default:
+			return nil, fmt.Errorf("%w; unknown option %s in %s", ErrInvalidMarker, key, comment)
+		}
+	}
+
+	return m, nil
+}
+
+// splitMarker splits a marker into its colon separated parts, ignoring any colons
+// within double quotes.
+func splitMarker(marker string) []string {
+	var parts []string
+
+	var inQuotes bool
+
+	start := 0
+
+	for i, char := range marker {
+		switch {
+		case char == '"':
+			inQuotes = !inQuotes
+		case char == ':' && !inQuotes:
+			parts = append(parts, marker[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, marker[start:])
+}
+
+// unquote removes the double quotes surrounding a value, if present.
+func unquote(value string) string {
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		return unquoted
+	}
+
+	return value
+}
+
+// defaultValue returns the go expression for the default value of a marker.
+func (m *marker) defaultValue() string {
+	if m.Default == "" {
+		return ""
+	}
+
+	if m.Type == "string" {
+		return strconv.Quote(unquote(m.Default))
+	}
+
+	return m.Default
+}
+
+// markers finds the workload marker for each element, replacing the value of the
+// element with a reference to the marked field of the spec and removing the
+// marker from the comments of the element.  The spec fields are added to fields
+// in the order in which they are first marked.
+func (e elements) markers(fields *[]specField) error {
+	for i := range e {
+		m, err := e[i].marker()
+		if err != nil {
+			return e[i].positionError(err)
+		}
+
+		if m == nil {
+			if err := e[i].Elements.markers(fields); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		ref, err := parseReference(varTag+":"+m.Type, specName+"."+strcase.ToCamel(m.Name))
+		if err != nil {
+			return e[i].positionError(fmt.Errorf("%w; %s", ErrInvalidMarker, err))
+		}
+
+		value, err := ref.Value()
+		if err != nil {
+			return e[i].positionError(fmt.Errorf("%w; %s", ErrInvalidMarker, err))
+		}
+
+		e[i].Type = varTag
+		e[i].Ref = ref
+		e[i].Value = value
+		e[i].Elements = nil
+
+		if err := addSpecField(fields, m, ref.Type); err != nil {
+			return e[i].positionError(err)
+		}
+	}
+
+	return nil
+}
+
+// marker returns the workload marker from the line or head comment of an
+// element, removing it from the comment.
+func (elem *element) marker() (*marker, error) {
+	if m, err := parseMarker(elem.LineComment); m != nil || err != nil {
+		elem.LineComment = ""
+
+		return m, err
+	}
+
+	lines := strings.Split(elem.HeadComment, "\n")
+
+	for i := range lines {
+		m, err := parseMarker(lines[i])
+		if m == nil && err == nil {
+			continue
+		}
+
+		elem.HeadComment = strings.Join(append(lines[:i:i], lines[i+1:]...), "\n")
+
+		return m, err
+	}
+
+	return nil, nil
+}
+
+// addSpecField adds the field for a marker to a set of spec fields.  Markers for
+// the same field must agree on their type and default value.
+func addSpecField(fields *[]specField, m *marker, typeName string) error {
+	field := specField{
+		Name:        strcase.ToCamel(m.Name),
+		JSONName:    m.Name,
+		Type:        typeName,
+		Default:     m.defaultValue(),
+		Description: m.Description,
+	}
+
+	for i, existing := range *fields {
+		if existing.Name != field.Name {
+			continue
+		}
+
+		if existing.Type != field.Type || existing.Default != field.Default {
+			return fmt.Errorf("%w; %s", ErrConflictingMarker, m.Name)
+		}
+
+		if existing.Description == "" {
+			(*fields)[i].Description = field.Description
+		}
+
+		return nil
+	}
+
+	*fields = append(*fields, field)
+
+	return nil
+}
+
+// newSpec returns the spec for a set of spec fields for the constructor
+// function with the given name.
+func newSpec(funcName string, fields []specField) *spec {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	typeName := strcase.ToCamel(strings.TrimPrefix(funcName, "New")) + "Spec"
+
+	return &spec{
+		TypeName:    typeName,
+		DefaultFunc: "Default" + typeName,
+		Fields:      fields,
+	}
+}
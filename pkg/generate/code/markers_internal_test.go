@@ -0,0 +1,93 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package code
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseMarker(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		comment string
+		want    *marker
+		wantErr bool
+	}{
+		{
+			name:    "ensure comment without marker returns nil",
+			comment: " This is the replicas field",
+			want:    nil,
+		},
+		{
+			name:    "ensure marker with default and type is parsed",
+			comment: " +workload:namespace:default=ingress-system:type=string",
+			want: &marker{
+				Name:    "namespace",
+				Type:    "string",
+				Default: "ingress-system",
+			},
+		},
+		{
+			name:    "ensure quoted default containing colons is parsed",
+			comment: `#+workload:image:default="nginx:1.17":description="the container image"`,
+			want: &marker{
+				Name:        "image",
+				Type:        "string",
+				Default:     `"nginx:1.17"`,
+				Description: "the container image",
+			},
+		},
+		{
+			name:    "ensure marker with head comment prefix is parsed",
+			comment: "// +workload:replicas:default=2:type=int32",
+			want: &marker{
+				Name:    "replicas",
+				Type:    "int32",
+				Default: "2",
+			},
+		},
+		{
+			name:    "ensure marker with unknown option returns an error",
+			comment: "+workload:replicas:required=true",
+			wantErr: true,
+		},
+		{
+			name:    "ensure marker without field name returns an error",
+			comment: "+workload::type=string",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := parseMarker(tt.comment)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseMarker() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_addSpecField(t *testing.T) {
+	t.Parallel()
+
+	fields := []specField{}
+
+	assert.NoError(t, addSpecField(&fields, &marker{Name: "namespace", Type: "string", Default: "ingress-system"}, "string"))
+	assert.NoError(t, addSpecField(&fields, &marker{Name: "namespace", Type: "string", Default: `"ingress-system"`}, "string"))
+	assert.Equal(t, []specField{
+		{Name: "Namespace", JSONName: "namespace", Type: "string", Default: `"ingress-system"`},
+	}, fields)
+
+	assert.Error(t, addSpecField(&fields, &marker{Name: "namespace", Type: "string", Default: "default"}, "string"))
+}
@@ -0,0 +1,244 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package code
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+
+	"github.com/nukleros/gener8s/pkg/generate/naming"
+	"github.com/nukleros/gener8s/pkg/manifests"
+)
+
+// pendingPerWorker is the number of generated objects per worker which may be
+// waiting to be emitted while an earlier object is still being generated.  It
+// bounds the memory used when a single object is slow to generate.
+const pendingPerWorker = 4
+
+// result represents the outcome of generating the object at an index.
+type result struct {
+	index  int
+	object *Object
+	err    error
+}
+
+// workers returns the number of objects which are generated concurrently for a
+// number of objects.
+func (objectOptions *ObjectOptions) workers(objects int) int {
+	workers := objectOptions.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	if workers > objects {
+		workers = objects
+	}
+
+	return workers
+}
+
+// StreamObjects generates the unstructured go code for each object of a set of
+// input manifests concurrently, and calls emit with each object in order, as
+// soon as it and all of the objects before it are generated.  Objects are named
+// as with GenerateSource, in order before they are generated, so that their
+// names do not depend on the order in which they are generated.
+//
+// Generation stops at the first object which fails to generate, when emit
+// returns an error, or when the context is done, in which case emit has been
+// called for exactly the objects before the failed object, as when generating
+// them one at a time.
+func StreamObjects(
+	ctx context.Context,
+	files *manifests.Manifests,
+	objectOptions *ObjectOptions,
+	emit func(*Object) error,
+) error {
+	resources := files.ExtractObjects()
+
+	// objects which fail to be named are reported once the objects before them
+	// are generated
+	named, nameErr := nameObjects(resources, objectOptions)
+	if len(named) == 0 {
+		return nameErr
+	}
+
+	workers := objectOptions.workers(len(named))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan int)
+	results := make(chan result, workers)
+	slots := make(chan struct{}, workers*pendingPerWorker)
+
+	// objects are dispatched in order, each taking a slot until it is emitted
+	go func() {
+		defer close(jobs)
+
+		for i := range named {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				if err := ctx.Err(); err != nil {
+					results <- result{index: i, err: fmt.Errorf("%w", err)}
+
+					continue
+				}
+
+				object, err := generateObject(named[i], objectOptions)
+				results <- result{index: i, object: object, err: err}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	pending := map[int]*result{}
+	next := 0
+
+	var err error
+
+	// results are drained after an error, so that no worker is left blocked
+	for generated := range results {
+		if err != nil {
+			continue
+		}
+
+		generated := generated
+		pending[generated.index] = &generated
+
+		for ; pending[next] != nil && err == nil; next++ {
+			current := pending[next]
+			delete(pending, next)
+
+			if err = current.err; err == nil {
+				err = emit(current.object)
+			}
+
+			<-slots
+		}
+
+		if err != nil {
+			cancel()
+		}
+	}
+
+	// the dispatcher only stops early when the context is done
+	if err == nil && next < len(named) {
+		err = fmt.Errorf("%w", ctx.Err())
+	}
+
+	if err == nil {
+		err = nameErr
+	}
+
+	return err
+}
+
+// nameObjects decodes and names a set of objects, in order, returning the named
+// objects before the first object which fails to be decoded or named, along
+// with its error.
+func nameObjects(resources []*manifests.Object, objectOptions *ObjectOptions) ([]*Object, error) {
+	namer := objectOptions.Namer
+
+	if namer == nil {
+		var err error
+		if namer, err = naming.New(""); err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+	}
+
+	named := make([]*Object, 0, len(resources))
+
+	for _, resource := range resources {
+		unstructuredObj, err := decodeObject(resource)
+		if err != nil {
+			return named, err
+		}
+
+		name, err := namer.Name(unstructuredObj, objectOptions.Name, len(resources), objectOptions.Constructor)
+		if err != nil {
+			return named, resource.Wrap(err)
+		}
+
+		named = append(named, &Object{Name: name, Resource: resource, Object: unstructuredObj})
+	}
+
+	return named, nil
+}
+
+// WriteObjects writes the unstructured go code for a set of input manifests to
+// a writer, without the declaration of the imports it requires, as the objects
+// are generated.  The imports are returned once all of the objects have been
+// written.  Objects are generated as with StreamObjects, so that the code of the
+// objects before any error is written.
+func WriteObjects(
+	ctx context.Context,
+	w io.Writer,
+	files *manifests.Manifests,
+	objectOptions *ObjectOptions,
+) ([]string, error) {
+	var imports []string
+
+	first := true
+
+	err := StreamObjects(ctx, files, objectOptions, func(object *Object) error {
+		imports = append(imports, object.Imports...)
+
+		if err := writeObject(w, object, first); err != nil {
+			return err
+		}
+
+		first = false
+
+		return nil
+	})
+
+	if len(imports) > 0 {
+		imports = uniqueSorted(imports)
+	}
+
+	return imports, err
+}
+
+// writeObject writes the go source code of an object, separated from the code
+// of the objects around it.
+func writeObject(w io.Writer, object *Object, first bool) error {
+	separator := "\n"
+	if first {
+		separator = "\n\n"
+	}
+
+	if _, err := io.WriteString(w, object.Code+separator); err != nil {
+		return fmt.Errorf("%w; unable to write object %s", err, object.Name)
+	}
+
+	return nil
+}
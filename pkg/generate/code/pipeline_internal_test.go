@@ -0,0 +1,272 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package code
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nukleros/gener8s/pkg/manifests"
+)
+
+// objectsPerFile is the number of objects in each manifest file of a synthetic
+// bundle.
+const objectsPerFile = 100
+
+// syntheticBundle returns a bundle of manifest files with a number of objects,
+// which are a mix of deployments, services and config maps.
+func syntheticBundle(objects int) *manifests.Manifests {
+	bundle := manifests.Manifests{}
+
+	var content strings.Builder
+
+	for i := 0; i < objects; i++ {
+		if content.Len() > 0 {
+			content.WriteString("---\n")
+		}
+
+		switch i % 3 {
+		case 0:
+			fmt.Fprintf(&content, `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app-%[1]d
+  namespace: platform
+  labels:
+    app: app-%[1]d
+spec:
+  replicas: 3
+  selector:
+    matchLabels:
+      app: app-%[1]d
+  template:
+    metadata:
+      labels:
+        app: app-%[1]d
+    spec:
+      containers:
+        - name: app
+          image: registry.example.com/app:%[1]d
+          args: ["--port", "8080"]
+          ports:
+            - containerPort: 8080
+          resources:
+            limits:
+              cpu: 500m
+              memory: 128Mi
+`, i)
+		case 1:
+			fmt.Fprintf(&content, `apiVersion: v1
+kind: Service
+metadata:
+  name: app-%[1]d
+  namespace: platform
+spec:
+  selector:
+    app: app-%[1]d
+  ports:
+    - port: 80
+      targetPort: 8080
+`, i)
+		default:
+			fmt.Fprintf(&content, `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-%[1]d
+  namespace: platform
+data:
+  config.yaml: |
+    listen: :8080
+    name: app-%[1]d
+`, i)
+		}
+
+		if (i+1)%objectsPerFile == 0 || i == objects-1 {
+			bundle = append(bundle, &manifests.Manifest{
+				Filename: fmt.Sprintf("bundle-%d.yaml", len(bundle)),
+				Content:  []byte(content.String()),
+			})
+
+			content.Reset()
+		}
+	}
+
+	return &bundle
+}
+
+func TestStreamObjects(t *testing.T) {
+	t.Parallel()
+
+	bundle := syntheticBundle(150)
+
+	serial, err := GenerateObjects(context.Background(), bundle, &ObjectOptions{Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, workers := range []int{0, 2, 16} {
+		workers := workers
+		t.Run(fmt.Sprintf("ensure objects are emitted in order with %d workers", workers), func(t *testing.T) {
+			t.Parallel()
+
+			var got []*Object
+
+			err := StreamObjects(context.Background(), bundle, &ObjectOptions{Workers: workers}, func(object *Object) error {
+				got = append(got, object)
+
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, serial, got)
+		})
+	}
+}
+
+func TestStreamObjects_errors(t *testing.T) {
+	t.Parallel()
+
+	errEmit := errors.New("emit failed")
+
+	// the invalid object is the sixth, in the second manifest file
+	invalid := append(*syntheticBundle(4), &manifests.Manifest{
+		Filename: "invalid.yaml",
+		Content:  []byte("kind: Service\nmetadata:\n  name: web\n---\nkind: Service\nspec: [\n---\nkind: ConfigMap\n"),
+	})
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		files     *manifests.Manifests
+		emitErr   error
+		wantNames []string
+		wantErr   string
+	}{
+		{
+			name:      "ensure the objects before the first invalid object are emitted",
+			ctx:       context.Background(),
+			files:     &invalid,
+			wantNames: []string{"deploymentApp0", "serviceApp1", "configMapApp2", "deploymentApp3", "serviceWeb"},
+			wantErr:   "invalid.yaml:6:1: failed to convert YAML to JSON",
+		},
+		{
+			name:      "ensure an error from emit stops generation",
+			ctx:       context.Background(),
+			files:     syntheticBundle(40),
+			emitErr:   errEmit,
+			wantNames: []string{"deploymentApp0"},
+			wantErr:   errEmit.Error(),
+		},
+		{
+			name:    "ensure generation stops when the context is done",
+			ctx:     cancelled,
+			files:   syntheticBundle(40),
+			wantErr: context.Canceled.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got []string
+
+			err := StreamObjects(tt.ctx, tt.files, &ObjectOptions{Workers: 4}, func(object *Object) error {
+				got = append(got, object.Name)
+
+				return tt.emitErr
+			})
+
+			assert.ErrorContains(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantNames, got)
+		})
+	}
+}
+
+func TestWriteObjects(t *testing.T) {
+	t.Parallel()
+
+	bundle := syntheticBundle(10)
+
+	objects, err := GenerateObjects(context.Background(), bundle, &ObjectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got strings.Builder
+
+	imports, err := WriteObjects(context.Background(), &got, bundle, &ObjectOptions{Workers: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, Join(objects).Code, got.String())
+	assert.Empty(t, imports)
+}
+
+// benchmarkSizes are the numbers of objects of the synthetic bundles which are
+// benchmarked.
+var benchmarkSizes = []int{100, 1000, 3000}
+
+func BenchmarkGenerateCode(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		bundle := syntheticBundle(size)
+
+		b.Run(fmt.Sprintf("objects=%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := GenerateCode(bundle, &ObjectOptions{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkWriteObjects(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		bundle := syntheticBundle(size)
+
+		for _, workers := range []int{1, 4, 0} {
+			b.Run(fmt.Sprintf("objects=%d/workers=%d", size, workers), func(b *testing.B) {
+				b.ReportAllocs()
+
+				for i := 0; i < b.N; i++ {
+					if _, err := WriteObjects(context.Background(), io.Discard, bundle, &ObjectOptions{Workers: workers}); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkGenerateYAML(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		bundle := syntheticBundle(size)
+
+		b.Run(fmt.Sprintf("objects=%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := GenerateYAML(bundle); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
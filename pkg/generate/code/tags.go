@@ -0,0 +1,165 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package code
+
+import (
+	"errors"
+	"fmt"
+	"go/parser"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrInvalidTag        = errors.New("invalid custom tag")
+	ErrDuplicateTag      = errors.New("custom tag is already registered")
+	ErrInvalidExpression = errors.New("invalid expression for custom tag")
+)
+
+// reservedTags are the tags which are handled by yaml or by gener8s itself, and
+// therefore may not have a custom handler.
+var reservedTags = map[string]bool{
+	"!!str": true, "!!int": true, "!!bool": true, "!!float": true, "!!null": true, "!!map": true,
+	"!!seq": true, "!!binary": true, "!!timestamp": true, "!!merge": true,
+	varTag: true, ifTag: true, rangeTag: true,
+}
+
+// DefaultRegistry is the registry of the custom tag handlers registered with
+// RegisterTag, which are available to all generation.
+var DefaultRegistry = NewRegistry()
+
+// Expression represents the go expression which a custom tag handler generates
+// for a tagged value.
+type Expression struct {
+	// Expr is the go expression which is written into the generated code in
+	// place of the tagged value.  It must be a value which may be stored in an
+	// unstructured object, such as a string, an int64 or a map[string]interface{}.
+	Expr string
+
+	// Type is the go type of the expression when it is a plain identifier which
+	// is declared as a parameter of generated constructor functions, as with a
+	// typed !!var reference.
+	Type string
+
+	// Imports are the import paths which the expression requires, each preceded
+	// by the name it is imported with and a space when the name is not the last
+	// element of the path.
+	Imports []string
+}
+
+// TagHandler generates the go expression for a yaml node with a custom tag,
+// such as !!secretRef, !!quantity or !env.  The node is given as decoded, so its
+// tag is available with ShortTag, including any arguments given with the tag in
+// the form of !!tag:arguments.
+type TagHandler interface {
+	HandleTag(node *yaml.Node) (*Expression, error)
+}
+
+// TagHandlerFunc is a function which implements TagHandler.
+type TagHandlerFunc func(node *yaml.Node) (*Expression, error)
+
+// HandleTag calls the function for the node.
+func (f TagHandlerFunc) HandleTag(node *yaml.Node) (*Expression, error) {
+	return f(node)
+}
+
+// Registry is a set of handlers for custom tags, which is safe for concurrent
+// use.
+type Registry struct {
+	mu       sync.RWMutex
+	handlers map[string]TagHandler
+}
+
+// NewRegistry returns an empty registry of custom tag handlers.
+func NewRegistry() *Registry {
+	return &Registry{handlers: map[string]TagHandler{}}
+}
+
+// RegisterTag registers a handler for a custom tag with the default registry.
+func RegisterTag(tag string, handler TagHandler) error {
+	return DefaultRegistry.Register(tag, handler)
+}
+
+// Register registers a handler for a custom tag, in its short form, such as
+// !!secretRef or !env.  The handler also handles the tag with arguments, in the
+// form of !!secretRef:arguments.  Tags handled by yaml or by gener8s itself may
+// not be registered, and each tag may only be registered once.
+func (registry *Registry) Register(tag string, handler TagHandler) error {
+	switch {
+	case !strings.HasPrefix(tag, "!") || strings.Trim(tag, "!") == "" || strings.ContainsAny(tag, ": \t"):
+		return fmt.Errorf("%w %q; must be in the form of !!name or !name", ErrInvalidTag, tag)
+	case reservedTags[tag]:
+		return fmt.Errorf("%w %q; tag is reserved", ErrInvalidTag, tag)
+	case handler == nil:
+		return fmt.Errorf("%w %q; missing handler", ErrInvalidTag, tag)
+	}
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if _, ok := registry.handlers[tag]; ok {
+		return fmt.Errorf("%w; %s", ErrDuplicateTag, tag)
+	}
+
+	registry.handlers[tag] = handler
+
+	return nil
+}
+
+// lookup returns the handler for a tag, with or without arguments, from the
+// registry or else from the default registry.
+func (registry *Registry) lookup(tag string) (TagHandler, bool) {
+	name := tag
+	if colon := strings.Index(tag, ":"); colon > 0 {
+		name = tag[:colon]
+	}
+
+	for _, r := range []*Registry{registry, DefaultRegistry} {
+		if r == nil {
+			continue
+		}
+
+		r.mu.RLock()
+		handler, ok := r.handlers[name]
+		r.mu.RUnlock()
+
+		if ok {
+			return handler, true
+		}
+	}
+
+	return nil, false
+}
+
+// decodeCustomTag decodes an element with a custom tag by substituting the
+// expression generated by the handler of the tag for its value.
+func (elem *element) decodeCustomTag(handler TagHandler, node *yaml.Node) error {
+	expression, err := handler.HandleTag(node)
+	if err != nil {
+		return fmt.Errorf("%w; unable to handle tag %s", err, elem.Type)
+	}
+
+	if expression == nil || strings.TrimSpace(expression.Expr) == "" {
+		return fmt.Errorf("%w %s; missing expression", ErrInvalidExpression, elem.Type)
+	}
+
+	if _, err := parser.ParseExpr(expression.Expr); err != nil {
+		return fmt.Errorf("%w %s; %s", ErrInvalidExpression, elem.Type, err)
+	}
+
+	ref := &reference{Expr: expression.Expr, Type: expression.Type, Imports: expression.Imports}
+
+	value, err := ref.Value()
+	if err != nil {
+		return fmt.Errorf("%w; unable to handle tag %s", err, elem.Type)
+	}
+
+	elem.Type = varTag
+	elem.Ref = ref
+	elem.Value = value
+
+	return nil
+}
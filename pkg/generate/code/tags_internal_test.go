@@ -0,0 +1,174 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package code
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestRegistry_Register(t *testing.T) {
+	t.Parallel()
+
+	handler := TagHandlerFunc(func(node *yaml.Node) (*Expression, error) {
+		return &Expression{Expr: node.Value}, nil
+	})
+
+	tests := []struct {
+		name    string
+		tags    []string
+		handler TagHandler
+		wantErr string
+	}{
+		{
+			name: "ensure a custom tag is registered",
+			tags: []string{"!!secretRef"},
+		},
+		{
+			name: "ensure a local custom tag is registered",
+			tags: []string{"!env"},
+		},
+		{
+			name:    "ensure a tag without a leading ! is rejected",
+			tags:    []string{"secretRef"},
+			wantErr: `invalid custom tag "secretRef"; must be in the form of !!name or !name`,
+		},
+		{
+			name:    "ensure a tag with arguments is rejected",
+			tags:    []string{"!!secretRef:name"},
+			wantErr: `invalid custom tag "!!secretRef:name"; must be in the form of !!name or !name`,
+		},
+		{
+			name:    "ensure a tag handled by gener8s is rejected",
+			tags:    []string{"!!var"},
+			wantErr: `invalid custom tag "!!var"; tag is reserved`,
+		},
+		{
+			name:    "ensure a tag handled by yaml is rejected",
+			tags:    []string{"!!str"},
+			wantErr: `invalid custom tag "!!str"; tag is reserved`,
+		},
+		{
+			name:    "ensure a tag is only registered once",
+			tags:    []string{"!!quantity", "!!quantity"},
+			wantErr: "custom tag is already registered; !!quantity",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			registry := NewRegistry()
+
+			var err error
+			for _, tag := range tt.tags {
+				if err = registry.Register(tag, handler); err != nil {
+					break
+				}
+			}
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_generate_customTags(t *testing.T) {
+	t.Parallel()
+
+	registry := NewRegistry()
+
+	if err := registry.Register("!!secretRef", TagHandlerFunc(func(node *yaml.Node) (*Expression, error) {
+		key := strings.TrimPrefix(node.ShortTag(), "!!secretRef")
+		if key == "" {
+			return nil, fmt.Errorf("missing key")
+		}
+
+		return &Expression{
+			Expr:    fmt.Sprintf("secrets.Ref(%q, %q)", node.Value, strings.TrimPrefix(key, ":")),
+			Imports: []string{"github.com/acme/secrets"},
+		}, nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := registry.Register("!!quantity", TagHandlerFunc(func(node *yaml.Node) (*Expression, error) {
+		return &Expression{Expr: node.Value, Type: "string"}, nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := registry.Register("!!invalid", TagHandlerFunc(func(node *yaml.Node) (*Expression, error) {
+		return &Expression{Expr: "secrets.Ref("}, nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		yaml        string
+		constructor bool
+		want        []string
+		wantImports []string
+		wantErr     string
+	}{
+		{
+			name:        "ensure a custom tag is generated with its expression and imports",
+			yaml:        "kind: Secret\ndata:\n  password: !!secretRef:password db-credentials",
+			want:        []string{`"password": secrets.Ref("db-credentials", "password"),`},
+			wantImports: []string{"github.com/acme/secrets"},
+		},
+		{
+			name:        "ensure a typed identifier from a custom tag is a constructor parameter",
+			yaml:        "kind: Pod\nspec:\n  limits:\n    cpu: !!quantity cpuLimit",
+			constructor: true,
+			want:        []string{"cpuLimit string", `"cpu": cpuLimit,`},
+		},
+		{
+			name:    "ensure errors from a custom tag handler are reported at the tagged element",
+			yaml:    "kind: Secret\ndata:\n  password: !!secretRef db-credentials",
+			wantErr: "3:13: missing key; unable to handle tag !!secretRef",
+		},
+		{
+			name:    "ensure an invalid expression from a custom tag handler is reported",
+			yaml:    "kind: Secret\ndata:\n  password: !!invalid db-credentials",
+			wantErr: "3:13: invalid expression for custom tag !!invalid; 1:13: expected ')', found 'EOF'",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := generate([]byte(tt.yaml), "object", tt.constructor, registry)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, want := range tt.want {
+				assert.Contains(t, got.Source, want)
+			}
+
+			assert.Equal(t, tt.wantImports, got.Imports)
+		})
+	}
+}
@@ -0,0 +1,393 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package code
+
+import (
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrInvalidVariableReference = errors.New("invalid variable reference")
+	ErrConflictingVariableType  = errors.New("variable is referenced with conflicting types")
+	ErrConflictingImport        = errors.New("import paths are imported with the same name")
+)
+
+const varTag = "!!var"
+
+// reference represents a variable reference, set with the !!var tag, which is
+// written into the generated code in place of a static value.  The tag may
+// carry the Go type of the reference (e.g. !!var:int32 replicas) and both the
+// type and the reference may be qualified with an import path
+// (e.g. !!var github.com/acme/app/config.Image), which may be preceded by the
+// name it is imported with in parentheses
+// (e.g. !!range:sidecar=sidecars:(corev1)k8s.io/api/core/v1.Container).
+type reference struct {
+	Expr    string
+	Type    string
+	Imports []string
+
+	// Line and Column are the position of the reference within the yaml content.
+	Line   int
+	Column int
+}
+
+// variable represents a typed variable which is declared as a parameter of a
+// generated constructor function.
+type variable struct {
+	Name string
+	Type string
+}
+
+// isVarTag determines if a yaml tag is a variable reference tag.
+func isVarTag(tag string) bool {
+	return tag == varTag || strings.HasPrefix(tag, varTag+":") || strings.HasPrefix(tag, varTag+"=")
+}
+
+// parseReference parses a variable reference from a !!var tag and its value.
+func parseReference(tag, value string) (*reference, error) {
+	ref := &reference{}
+
+	if strings.HasPrefix(tag, varTag+"=") {
+		return nil, fmt.Errorf("%w; the expression of a scalar follows the tag after a space, as in %s replicas, found tag %s",
+			ErrInvalidVariableReference, varTag, tag)
+	}
+
+	expr := strings.TrimSpace(value)
+	if expr == "" {
+		return nil, fmt.Errorf("%w; missing expression for tag %s", ErrInvalidVariableReference, tag)
+	}
+
+	var err error
+
+	if ref.Expr, err = ref.qualify(expr); err != nil {
+		return nil, err
+	}
+
+	if typeName := strings.TrimPrefix(tag, varTag); typeName != "" {
+		if ref.Type, err = ref.qualify(strings.TrimPrefix(typeName, ":")); err != nil {
+			return nil, err
+		}
+
+		if ref.Type == "" {
+			return nil, fmt.Errorf("%w; missing type for tag %s", ErrInvalidVariableReference, tag)
+		}
+	}
+
+	// unstructured objects only store signed 64 bit integers, which do not hold
+	// all of the values of unsigned 64 bit integers
+	switch ref.Type {
+	case "uint", "uint64", "uintptr":
+		return nil, fmt.Errorf("%w; type %s of tag %s may hold values which an unstructured object cannot store, "+
+			"reference a conversion of the value to int64 instead", ErrInvalidVariableReference, ref.Type, tag)
+	}
+
+	return ref, nil
+}
+
+// qualify returns the name as it is referenced in go code, recording the import
+// needed for the reference if the name is qualified with an import path.  The
+// package is referenced by the name given in parentheses before the import path,
+// or otherwise by the name assumed from the import path, which is given to the
+// import when it is not the last element of the path.
+func (ref *reference) qualify(name string) (string, error) {
+	slash := strings.LastIndex(name, "/")
+	if slash < 0 {
+		return name, nil
+	}
+
+	dot := selectorIndex(name[slash:])
+	if dot < 0 {
+		return name, nil
+	}
+
+	importPath := name[:slash+dot]
+	prefix := ""
+
+	// retain any pointer or slice prefixes on a qualified type
+	if trimmed := strings.TrimLeft(importPath, "*[]"); trimmed != importPath {
+		prefix = importPath[:len(importPath)-len(trimmed)]
+		importPath = trimmed
+	}
+
+	pkgName := packageName(importPath)
+
+	if strings.HasPrefix(importPath, "(") {
+		closing := strings.Index(importPath, ")")
+		if closing < 0 || !token.IsIdentifier(importPath[1:closing]) {
+			return "", fmt.Errorf("%w; invalid import name in %s", ErrInvalidVariableReference, name)
+		}
+
+		pkgName, importPath = importPath[1:closing], importPath[closing+1:]
+	}
+
+	if pkgName == "" || importPath == "" {
+		return "", fmt.Errorf("%w; unable to determine the package name of %s, "+
+			"give it before the import path in parentheses", ErrInvalidVariableReference, name)
+	}
+
+	imp := importPath
+	if pkgName != path.Base(importPath) {
+		imp = pkgName + " " + importPath
+	}
+
+	ref.Imports = append(ref.Imports, imp)
+
+	return prefix + pkgName + name[slash+dot:], nil
+}
+
+// selectorIndex returns the index of the dot which separates the last element of
+// an import path from the name which is selected from the package, or -1 if
+// there is none.  Elements with a major version suffix, as used by gopkg.in
+// (e.g. yaml.v3), are part of the import path.
+func selectorIndex(element string) int {
+	dot := strings.Index(element, ".")
+
+	for dot >= 0 && majorVersion(element[dot+1:]) {
+		next := strings.Index(element[dot+1:], ".")
+		if next < 0 {
+			return -1
+		}
+
+		dot += next + 1
+	}
+
+	return dot
+}
+
+// majorVersion determines if a name starts with a major version, such as v3,
+// which is followed by the end of the name or a dot.
+func majorVersion(name string) bool {
+	end := strings.Index(name, ".")
+	if end < 0 {
+		end = len(name)
+	}
+
+	if end < 2 || name[0] != 'v' {
+		return false
+	}
+
+	_, err := strconv.Atoi(name[1:end])
+
+	return err == nil
+}
+
+// packageName returns the name of the package of an import path as assumed from
+// the path, following the conventions which goimports assumes.  Major version
+// elements (e.g. /v2) and suffixes (e.g. yaml.v3) are dropped, as are go-
+// prefixes and anything from the first character which may not be part of an
+// identifier.
+func packageName(importPath string) string {
+	base := path.Base(importPath)
+
+	if majorVersion(base) && path.Dir(importPath) != "." {
+		base = path.Base(path.Dir(importPath))
+	}
+
+	base = strings.TrimPrefix(base, "go-")
+
+	if i := strings.IndexFunc(base, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}); i >= 0 {
+		base = base[:i]
+	}
+
+	return base
+}
+
+// importName returns the name which an import, as recorded by a reference, is
+// referenced by.
+func importName(imp string) string {
+	if space := strings.Index(imp, " "); space >= 0 {
+		return imp[:space]
+	}
+
+	return path.Base(imp)
+}
+
+// ImportSpec returns the import spec of an import of generated go source code.
+// Imports are an import path, which is preceded by the name it is imported with
+// and a space when the name is not the last element of the path.
+func ImportSpec(imp string) string {
+	if space := strings.Index(imp, " "); space >= 0 {
+		return imp[:space] + " " + strconv.Quote(imp[space+1:])
+	}
+
+	return strconv.Quote(imp)
+}
+
+// Value returns the go expression for the reference as it is stored in an
+// unstructured object.  Unstructured objects only store 64 bit numbers, so
+// references to other numeric types are converted.  References of any other
+// type which is not a json type, such as a struct or a named type, are rejected
+// as the object panics when it is deep copied.  References without a type are
+// stored as they are, as their type is unknown.
+func (ref *reference) Value() (string, error) {
+	switch ref.Type {
+	case "int", "int8", "int16", "int32", "uint8", "uint16", "uint32":
+		return fmt.Sprintf("int64(%s)", ref.Expr), nil
+	case "float32":
+		return fmt.Sprintf("float64(%s)", ref.Expr), nil
+	case "", "string", "bool", "int64", "float64", "interface{}", "map[string]interface{}", "[]interface{}":
+		return ref.Expr, nil
+	}
+
+	if _, err := parser.ParseExpr(ref.Type); err != nil {
+		return "", fmt.Errorf("%w; %q is not a valid go type, %s", ErrInvalidVariableReference, ref.Type, err)
+	}
+
+	return "", fmt.Errorf("%w; type %s of %s is not a type which an unstructured object can store, "+
+		"reference a conversion of the value to string, int64, float64, bool, map[string]interface{} "+
+		"or []interface{} instead, such as string(%s) for a named string type or a map converted with "+
+		"runtime.DefaultUnstructuredConverter.ToUnstructured for a struct",
+		ErrInvalidVariableReference, ref.Type, ref.Expr, ref.Expr)
+}
+
+// variable returns the variable declared by the reference.  Only typed
+// references to a plain identifier declare a variable, as the type of any other
+// expression is unknown.
+func (ref *reference) variable() *variable {
+	if ref.Type == "" || !token.IsIdentifier(ref.Expr) {
+		return nil
+	}
+
+	return &variable{Name: ref.Expr, Type: ref.Type}
+}
+
+// references returns all variable references for a set of elements.  References
+// without a position are given the position of the element they belong to.
+func (e elements) references() []*reference {
+	var refs []*reference
+
+	for i := range e {
+		elemRefs := []*reference{e[i].KeyRef, e[i].Ref}
+
+		if e[i].Flow != nil {
+			elemRefs = append(elemRefs, e[i].Flow.Cond, e[i].Flow.RangeList)
+		}
+
+		for _, ref := range elemRefs {
+			if ref == nil {
+				continue
+			}
+
+			if ref.Line == 0 {
+				ref.Line, ref.Column = e[i].Line, e[i].Column
+			}
+
+			refs = append(refs, ref)
+		}
+
+		refs = append(refs, e[i].Elements.references()...)
+	}
+
+	return refs
+}
+
+// validateReferences returns an error, at the position of the reference, for
+// the first reference with an expression or type which is not valid go, so that
+// it is reported at its field rather than when the generated code is formatted.
+func validateReferences(refs []*reference) error {
+	for _, ref := range refs {
+		if _, err := parser.ParseExpr(ref.Expr); err != nil {
+			return positionError(ref.Line, ref.Column, fmt.Errorf(
+				"%w; %q is not a valid go expression, %s", ErrInvalidVariableReference, ref.Expr, err,
+			))
+		}
+
+		if ref.Type == "" {
+			continue
+		}
+
+		if _, err := parser.ParseExpr(ref.Type); err != nil {
+			return positionError(ref.Line, ref.Column, fmt.Errorf(
+				"%w; %q is not a valid go type, %s", ErrInvalidVariableReference, ref.Type, err,
+			))
+		}
+	}
+
+	return nil
+}
+
+// variables returns the variables declared by a set of references in the order
+// in which they are first referenced.
+func variables(refs []*reference) ([]variable, error) {
+	var vars []variable
+
+	declared := map[string]string{}
+
+	for _, ref := range refs {
+		v := ref.variable()
+		if v == nil {
+			continue
+		}
+
+		if existing, ok := declared[v.Name]; ok {
+			if existing != v.Type {
+				return nil, positionError(ref.Line, ref.Column, fmt.Errorf(
+					"%w; %s is referenced as both %s and %s", ErrConflictingVariableType, v.Name, existing, v.Type,
+				))
+			}
+
+			continue
+		}
+
+		declared[v.Name] = v.Type
+		vars = append(vars, *v)
+	}
+
+	return vars, nil
+}
+
+// imports returns the sorted and deduplicated imports needed by a set of
+// references, returning an error if different import paths are imported with
+// the same name.
+func imports(refs []*reference) ([]string, error) {
+	var paths []string
+
+	named := map[string]string{}
+
+	for _, ref := range refs {
+		for _, imp := range ref.Imports {
+			name := importName(imp)
+
+			if existing, ok := named[name]; ok && existing != imp {
+				return nil, positionError(ref.Line, ref.Column, fmt.Errorf(
+					"%w; %q and %q are both imported as %s, give one a name before its import path in parentheses",
+					ErrConflictingImport, existing, imp, name,
+				))
+			}
+
+			named[name] = imp
+			paths = append(paths, imp)
+		}
+	}
+
+	return uniqueSorted(paths), nil
+}
+
+// uniqueSorted removes duplicates from a set of strings and sorts them.
+func uniqueSorted(strs []string) []string {
+	set := map[string]bool{}
+
+	var result []string
+
+	for _, str := range strs {
+		if !set[str] {
+			set[str] = true
+			result = append(result, str)
+		}
+	}
+
+	sort.Strings(result)
+
+	return result
+}
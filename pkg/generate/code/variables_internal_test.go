@@ -0,0 +1,298 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package code
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func Test_parseReference(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		tag   string
+		value string
+	}
+
+	tests := []struct {
+		name         string
+		args         args
+		want         *reference
+		wantValue    string
+		wantValueErr bool
+		wantErr      bool
+	}{
+		{
+			name: "ensure untyped reference is returned as is",
+			args: args{
+				tag:   "!!var",
+				value: "webstoreLabel",
+			},
+			want: &reference{
+				Expr: "webstoreLabel",
+			},
+			wantValue: "webstoreLabel",
+		},
+		{
+			name: "ensure typed reference converts to an unstructured value",
+			args: args{
+				tag:   "!!var:int32",
+				value: "spec.Replicas",
+			},
+			want: &reference{
+				Expr: "spec.Replicas",
+				Type: "int32",
+			},
+			wantValue: "int64(spec.Replicas)",
+		},
+		{
+			name: "ensure qualified reference records its import",
+			args: args{
+				tag:   "!!var",
+				value: "github.com/acme/app/config.Image",
+			},
+			want: &reference{
+				Expr:    "config.Image",
+				Imports: []string{"github.com/acme/app/config"},
+			},
+			wantValue: "config.Image",
+		},
+		{
+			name: "ensure qualified type records its import",
+			args: args{
+				tag:   "!!var:*github.com/acme/app/api/v1.Port",
+				value: "port",
+			},
+			want: &reference{
+				Expr:    "port",
+				Type:    "*api.Port",
+				Imports: []string{"api github.com/acme/app/api/v1"},
+			},
+			wantValueErr: true,
+		},
+		{
+			name: "ensure qualified type is referenced by the name given to its import",
+			args: args{
+				tag:   "!!var:[](corev1)k8s.io/api/core/v1.Container",
+				value: "sidecars",
+			},
+			want: &reference{
+				Expr:    "sidecars",
+				Type:    "[]corev1.Container",
+				Imports: []string{"corev1 k8s.io/api/core/v1"},
+			},
+			wantValueErr: true,
+		},
+		{
+			name: "ensure version suffixes and hyphens are not part of the assumed package name",
+			args: args{
+				tag:   "!!var:gopkg.in/yaml.v3.Node",
+				value: "github.com/acme/go-config.Default.Node",
+			},
+			want: &reference{
+				Expr:    "config.Default.Node",
+				Type:    "yaml.Node",
+				Imports: []string{"config github.com/acme/go-config", "yaml gopkg.in/yaml.v3"},
+			},
+			wantValueErr: true,
+		},
+		{
+			name: "ensure invalid import name returns an error",
+			args: args{
+				tag:   "!!var:(core-v1)k8s.io/api/core/v1.Container",
+				value: "sidecar",
+			},
+			wantErr: true,
+		},
+		{
+			name: "ensure unsigned 64 bit type returns an error",
+			args: args{
+				tag:   "!!var:uint64",
+				value: "size",
+			},
+			wantErr: true,
+		},
+		{
+			name: "ensure missing expression returns an error",
+			args: args{
+				tag:   "!!var:string",
+				value: " ",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := parseReference(tt.args.tag, tt.args.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseReference() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			assert.Equal(t, tt.want, got)
+			value, err := got.Value()
+			if (err != nil) != tt.wantValueErr {
+				t.Errorf("Value() error = %v, wantValueErr %v", err, tt.wantValueErr)
+			}
+			assert.Equal(t, tt.wantValue, value)
+		})
+	}
+}
+
+func Test_reference_Value(t *testing.T) {
+	t.Parallel()
+
+	type label string
+
+	tests := []struct {
+		name      string
+		ref       *reference
+		want      string
+		wantValue interface{}
+		wantErr   bool
+	}{
+		{
+			name:      "ensure narrow integers are converted to int64",
+			ref:       &reference{Expr: "replicas", Type: "int32"},
+			want:      "int64(replicas)",
+			wantValue: int64(int32(3)),
+		},
+		{
+			name:      "ensure narrow floats are converted to float64",
+			ref:       &reference{Expr: "ratio", Type: "float32"},
+			want:      "float64(ratio)",
+			wantValue: float64(float32(0.5)),
+		},
+		{
+			name:      "ensure json types are stored as is",
+			ref:       &reference{Expr: "labels", Type: "map[string]interface{}"},
+			want:      "labels",
+			wantValue: map[string]interface{}{"app": "web"},
+		},
+		{
+			name:      "ensure named types are converted by the reference",
+			ref:       &reference{Expr: "string(team)", Type: "string"},
+			want:      "string(team)",
+			wantValue: string(label("shop")),
+		},
+		{
+			name:    "ensure named types are rejected",
+			ref:     &reference{Expr: "image", Type: "config.Image"},
+			wantErr: true,
+		},
+		{
+			name:    "ensure structs are rejected",
+			ref:     &reference{Expr: "sidecar", Type: "corev1.Container"},
+			wantErr: true,
+		},
+		{
+			name:    "ensure slices of other than interface{} are rejected",
+			ref:     &reference{Expr: "args", Type: "[]string"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := tt.ref.Value()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Value() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			assert.Equal(t, tt.want, got)
+
+			if tt.wantErr {
+				return
+			}
+
+			// the value which the expression evaluates to is deep copied
+			// without panicking when set on an unstructured object
+			object := &unstructured.Unstructured{Object: map[string]interface{}{
+				"spec": map[string]interface{}{"field": tt.wantValue},
+			}}
+
+			assert.NotPanics(t, func() {
+				assert.Equal(t, object, object.DeepCopy())
+			})
+		})
+	}
+}
+
+func Test_variables(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		refs    []*reference
+		want    []variable
+		wantErr bool
+	}{
+		{
+			name: "ensure only typed identifiers are declared once",
+			refs: []*reference{
+				{Expr: "replicas", Type: "int32"},
+				{Expr: "webstoreLabel"},
+				{Expr: "spec.Image", Type: "string"},
+				{Expr: "replicas", Type: "int32"},
+			},
+			want: []variable{
+				{Name: "replicas", Type: "int32"},
+			},
+		},
+		{
+			name: "ensure conflicting types return an error",
+			refs: []*reference{
+				{Expr: "replicas", Type: "int32"},
+				{Expr: "replicas", Type: "string"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := variables(tt.refs)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("variables() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_imports(t *testing.T) {
+	t.Parallel()
+
+	got, err := imports([]*reference{
+		{Expr: "corev1.Container", Imports: []string{"corev1 k8s.io/api/core/v1"}},
+		{Expr: "config.Image", Imports: []string{"github.com/acme/app/config"}},
+		{Expr: "corev1.Volume", Imports: []string{"corev1 k8s.io/api/core/v1"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []string{"corev1 k8s.io/api/core/v1", "github.com/acme/app/config"}, got)
+
+	_, err = imports([]*reference{
+		{Expr: "v1.Container", Imports: []string{"k8s.io/api/core/v1"}, Line: 3, Column: 5},
+		{Expr: "v1.Deployment", Imports: []string{"v1 k8s.io/api/apps/v1"}, Line: 4, Column: 5},
+	})
+	assert.ErrorIs(t, err, ErrConflictingImport)
+}
@@ -0,0 +1,313 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+// Package generate provides a stable API for generating go source code, and rbac,
+// for the Kubernetes objects in yaml manifests.
+package generate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/nukleros/gener8s/pkg/generate/code"
+	"github.com/nukleros/gener8s/pkg/generate/naming"
+	"github.com/nukleros/gener8s/pkg/generate/rbac"
+	"github.com/nukleros/gener8s/pkg/manifests"
+)
+
+var ErrUnknownFormat = errors.New("unknown output format")
+
+// defaults for the options of a generator, which match the defaults of the
+// flags of the equivalent commands.
+const (
+	defaultRoleName         = "manager-role"
+	defaultRBACVariableName = "resourceObj"
+)
+
+// Format represents the form of the output of a generator.
+type Format string
+
+const (
+	// FormatGo generates unstructured go code for each object.
+	FormatGo Format = "go"
+
+	// FormatRBACYAML generates the rbac needed to manage the objects as yaml.
+	FormatRBACYAML Format = "rbac-yaml"
+
+	// FormatRBACMarkers generates the rbac needed to manage the objects as
+	// kubebuilder markers.
+	FormatRBACMarkers Format = "rbac-markers"
+
+	// FormatRBACGo generates the rbac needed to manage the objects as
+	// unstructured go code.
+	FormatRBACGo Format = "rbac-go"
+)
+
+// Generator generates output for the objects in yaml manifests.  It is
+// configured with options when created, and is safe for concurrent use.
+type Generator struct {
+	format        Format
+	values        map[string]interface{}
+	name          string
+	constructor   bool
+	filter        manifests.Filter
+	duplicates    manifests.DuplicatePolicy
+	roleName      string
+	verbs         []string
+	resourceNames bool
+	tags          *code.Registry
+	workers       int
+	namePattern   string
+	collection    string
+	kindOrder     []string
+}
+
+// Option configures a Generator.
+type Option func(*Generator)
+
+// New returns a Generator configured with the options.  By default, it generates
+// unstructured go variables named after the kind and name of each object, and
+// fails on objects defined more than once with different content.
+func New(opts ...Option) *Generator {
+	generator := &Generator{
+		format:     FormatGo,
+		roleName:   defaultRoleName,
+		verbs:      rbac.DefaultResourceVerbs(),
+		duplicates: manifests.DuplicatesError,
+	}
+
+	for _, opt := range opts {
+		opt(generator)
+	}
+
+	return generator
+}
+
+// WithFormat sets the form of the output.
+func WithFormat(format Format) Option {
+	return func(generator *Generator) {
+		generator.format = format
+	}
+}
+
+// WithValues sets the values which resolve templating, and fields with !!tpl
+// tags, in the manifests.
+func WithValues(values map[string]interface{}) Option {
+	return func(generator *Generator) {
+		generator.values = values
+	}
+}
+
+// WithName sets the name of the variable, or constructor function, of a single
+// object, which prefixes the names of multiple objects.  For the rbac go format,
+// it sets the name of the variable of the rbac objects.
+func WithName(name string) Option {
+	return func(generator *Generator) {
+		generator.name = name
+	}
+}
+
+// WithConstructor determines if constructor functions, with typed variable
+// references as parameters, are generated rather than variables.
+func WithConstructor(constructor bool) Option {
+	return func(generator *Generator) {
+		generator.constructor = constructor
+	}
+}
+
+// WithTags sets the registry of the handlers of custom tags, which are used in
+// addition to those registered with code.RegisterTag.
+func WithTags(tags *code.Registry) Option {
+	return func(generator *Generator) {
+		generator.tags = tags
+	}
+}
+
+// WithNamePattern sets the pattern which objects, and roles, are named with
+// when no name is given, or which follows the name when multiple objects are
+// generated.  See naming.New for the form of the pattern.
+func WithNamePattern(pattern string) Option {
+	return func(generator *Generator) {
+		generator.namePattern = pattern
+	}
+}
+
+// WithCollection adds a function with a name to the go output which returns all
+// of the objects as a []client.Object in install order, sorted by the kind
+// order, along with a function suffixed with Teardown which returns them in
+// reverse.  The kind order defaults to code.DefaultKindOrder.
+func WithCollection(name string, kindOrder ...string) Option {
+	return func(generator *Generator) {
+		generator.collection, generator.kindOrder = name, kindOrder
+	}
+}
+
+// WithWorkers sets the maximum number of objects which are generated
+// concurrently, which defaults to the number of CPUs.
+func WithWorkers(workers int) Option {
+	return func(generator *Generator) {
+		generator.workers = workers
+	}
+}
+
+// WithFilter sets the criteria for selecting the objects to generate.
+func WithFilter(filter manifests.Filter) Option {
+	return func(generator *Generator) {
+		generator.filter = filter
+	}
+}
+
+// WithDuplicates sets the policy which resolves objects defined more than once,
+// before the objects are selected.  The default, manifests.DuplicatesError, is
+// the same as that of the --duplicates flag, and fails unless the definitions of
+// an object are identical.
+func WithDuplicates(policy manifests.DuplicatePolicy) Option {
+	return func(generator *Generator) {
+		generator.duplicates = policy
+	}
+}
+
+// WithRoleName sets the name of the role of the rbac formats.
+func WithRoleName(roleName string) Option {
+	return func(generator *Generator) {
+		generator.roleName = roleName
+	}
+}
+
+// WithVerbs sets the verbs of the rbac rules of the rbac formats.
+func WithVerbs(verbs ...string) Option {
+	return func(generator *Generator) {
+		generator.verbs = verbs
+	}
+}
+
+// WithResourceNames determines if the rbac rules of the rbac formats are locked
+// down to the names of the objects.
+func WithResourceNames(resourceNames bool) Option {
+	return func(generator *Generator) {
+		generator.resourceNames = resourceNames
+	}
+}
+
+// Result represents the output of a generator.
+type Result struct {
+	// Format is the form of the output.
+	Format Format
+
+	// Output is the complete output, as written by the equivalent command.
+	Output string
+
+	// Imports are the import paths which the output requires, for the go
+	// format.  The output includes their declaration.
+	Imports []string
+
+	// Objects are the generated go source code of each object, along with the
+	// object and its position within its manifest file, for the go format.
+	Objects []*code.Object
+
+	// Skipped are the definitions of objects defined more than once which were
+	// resolved by the duplicate policy, each with the definition which was kept.
+	Skipped []manifests.Skipped
+}
+
+// Generate generates the output for the objects of a set of manifests, which
+// must have their content loaded.  Generation stops with the error of the
+// context when it is done, which is checked for each object.
+func (generator *Generator) Generate(ctx context.Context, files *manifests.Manifests) (*Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	deduplicated, skipped, err := files.Deduplicate(generator.duplicates)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	selected, err := deduplicated.Filter(&generator.filter, generator.values)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	result := &Result{Format: generator.format, Skipped: skipped}
+
+	switch generator.format {
+	case FormatGo:
+		namer, err := naming.New(generator.namePattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+
+		objects, err := code.GenerateObjects(ctx, selected, &code.ObjectOptions{
+			Values:      generator.values,
+			Name:        generator.name,
+			Constructor: generator.constructor,
+			Tags:        generator.tags,
+			Workers:     generator.workers,
+			Namer:       namer,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+
+		source := code.Join(objects)
+
+		if generator.collection != "" {
+			collection := &code.Collection{Name: generator.collection, KindOrder: generator.kindOrder}
+			collection.Add(objects...)
+
+			collectionSource, err := collection.Source()
+			if err != nil {
+				return nil, fmt.Errorf("%w", err)
+			}
+
+			source.Append(collectionSource)
+		}
+
+		result.Output, result.Imports, result.Objects = source.String(), source.Imports, objects
+	case FormatRBACYAML:
+		result.Output, err = rbac.GenerateYAML(ctx, selected, generator.rbacOptions())
+	case FormatRBACMarkers:
+		result.Output, err = rbac.GenerateMarkers(ctx, selected, generator.rbacOptions())
+	case FormatRBACGo:
+		result.Output, err = rbac.GenerateCode(ctx, selected, generator.rbacOptions())
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownFormat, generator.format)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return result, nil
+}
+
+// GenerateContent generates the output for the objects of yaml, or json,
+// manifest content.  Files included by the content are resolved relative to the
+// current directory.
+func (generator *Generator) GenerateContent(ctx context.Context, content []byte) (*Result, error) {
+	manifest, err := manifests.FromReader(bytes.NewReader(content), "")
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return generator.Generate(ctx, &manifests.Manifests{manifest})
+}
+
+// rbacOptions returns the options of the rbac generators for the generator.
+func (generator *Generator) rbacOptions() *rbac.Options {
+	rbacOptions := &rbac.Options{
+		RoleName:         generator.roleName,
+		Verbs:            generator.verbs,
+		UseResourceNames: generator.resourceNames,
+		VariableName:     generator.name,
+		NamePattern:      generator.namePattern,
+	}
+
+	if rbacOptions.VariableName == "" {
+		rbacOptions.VariableName = defaultRBACVariableName
+	}
+
+	return rbacOptions
+}
@@ -0,0 +1,153 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package generate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nukleros/gener8s/pkg/manifests"
+)
+
+const testManifests = `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: shop
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: shop
+  labels:
+    app: '{{ .name }}'
+spec:
+  replicas: !!var:int32 replicas
+`
+
+func TestGenerator_Generate(t *testing.T) {
+	t.Parallel()
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		generator *Generator
+		contains  []string
+		names     []string
+		wantErr   error
+	}{
+		{
+			name:      "ensure objects are generated as go variables by default",
+			ctx:       context.Background(),
+			generator: New(WithValues(map[string]interface{}{"name": "web"})),
+			contains:  []string{"var serviceWeb = &unstructured.Unstructured{", "var deploymentWeb = &unstructured.Unstructured{"},
+			names:     []string{"serviceWeb", "deploymentWeb"},
+		},
+		{
+			name: "ensure constructors are named and filtered by the options",
+			ctx:  context.Background(),
+			generator: New(
+				WithValues(map[string]interface{}{"name": "web"}),
+				WithName("frontend"),
+				WithConstructor(true),
+				WithFilter(manifests.Filter{Kinds: []string{"Deployment"}}),
+			),
+			contains: []string{"func NewFrontend(replicas int32) *unstructured.Unstructured {"},
+			names:    []string{"NewFrontend"},
+		},
+		{
+			name: "ensure a collection of the objects is generated in the kind order",
+			ctx:  context.Background(),
+			generator: New(
+				WithValues(map[string]interface{}{"name": "web"}),
+				WithConstructor(true),
+				WithCollection("Objects", "Deployment", "*"),
+			),
+			contains: []string{
+				`"sigs.k8s.io/controller-runtime/pkg/client"`,
+				"func Objects(replicas int32) []client.Object {\n\treturn []client.Object{\n\t\tNewDeploymentWeb(replicas),\n\t\tNewServiceWeb(),",
+				"func ObjectsTeardown(replicas int32) []client.Object {",
+			},
+			names: []string{"NewServiceWeb", "NewDeploymentWeb"},
+		},
+		{
+			name: "ensure rbac is generated in the rbac formats",
+			ctx:  context.Background(),
+			generator: New(
+				WithValues(map[string]interface{}{"name": "web"}),
+				WithFormat(FormatRBACMarkers),
+				WithVerbs("get", "list"),
+			),
+			contains: []string{"// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list"},
+		},
+		{
+			name:      "ensure unknown formats are rejected",
+			ctx:       context.Background(),
+			generator: New(WithFormat("python")),
+			wantErr:   ErrUnknownFormat,
+		},
+		{
+			name:      "ensure unknown duplicate policies are rejected",
+			ctx:       context.Background(),
+			generator: New(WithDuplicates("keep-all")),
+			wantErr:   manifests.ErrUnknownDuplicatePolicy,
+		},
+		{
+			name:      "ensure generation stops when the context is done",
+			ctx:       canceled,
+			generator: New(),
+			wantErr:   context.Canceled,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := tt.generator.GenerateContent(tt.ctx, []byte(testManifests))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Generate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			for _, want := range tt.contains {
+				assert.Contains(t, got.Output, want)
+			}
+
+			var names []string
+			for _, object := range got.Objects {
+				names = append(names, object.Name)
+			}
+
+			assert.Equal(t, tt.names, names)
+		})
+	}
+}
+
+func TestGenerator_Generate_positions(t *testing.T) {
+	t.Parallel()
+
+	got, err := New(WithValues(map[string]interface{}{"name": "web"})).GenerateContent(
+		context.Background(),
+		[]byte(testManifests),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, 1, got.Objects[0].Resource.Line)
+	assert.Equal(t, 7, got.Objects[1].Resource.Line)
+	assert.Equal(t, "shop", got.Objects[1].Object.GetNamespace())
+}
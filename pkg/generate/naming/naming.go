@@ -0,0 +1,178 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package naming
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/token"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"unicode"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/iancoleman/strcase"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var ErrInvalidPattern = errors.New("invalid naming pattern")
+
+// DefaultPattern is the pattern which objects are named with by default, after
+// their kind and name.
+const DefaultPattern = "{{ .Kind }}-{{ .Name }}"
+
+// fallbackName is the name of an object for which the pattern yields no name,
+// and the prefix of names which would otherwise start with a digit.
+const fallbackName = "object"
+
+// reserved are the identifiers which may not name a generated variable, as they
+// are either predeclared or are referenced by the generated code.
+var reserved = map[string]bool{
+	"any": true, "bool": true, "byte": true, "comparable": true, "complex64": true, "complex128": true,
+	"error": true, "float32": true, "float64": true, "int": true, "int8": true, "int16": true,
+	"int32": true, "int64": true, "rune": true, "string": true, "uint": true, "uint8": true,
+	"uint16": true, "uint32": true, "uint64": true, "uintptr": true, "true": true, "false": true,
+	"iota": true, "nil": true, "append": true, "cap": true, "close": true, "complex": true,
+	"copy": true, "delete": true, "imag": true, "len": true, "make": true, "new": true,
+	"panic": true, "print": true, "println": true, "real": true, "recover": true,
+	"unstructured": true,
+}
+
+// Object is an object which may be named, such as an unstructured object or a
+// typed object.
+type Object interface {
+	GetName() string
+	GetNamespace() string
+	GetObjectKind() schema.ObjectKind
+}
+
+// Fields represents the fields of an object which are available to the naming
+// pattern.
+type Fields struct {
+	Kind      string
+	Name      string
+	Namespace string
+	Group     string
+	Version   string
+}
+
+// FieldsOf returns the naming fields of an object.
+func FieldsOf(object Object) *Fields {
+	gvk := object.GetObjectKind().GroupVersionKind()
+
+	return &Fields{
+		Kind:      gvk.Kind,
+		Name:      object.GetName(),
+		Namespace: object.GetNamespace(),
+		Group:     gvk.Group,
+		Version:   gvk.Version,
+	}
+}
+
+// Namer names the variables, or constructor functions, of generated objects.
+// Names are sanitised to be valid go identifiers, and names which have already
+// been given are suffixed with a number, starting from 2, so that the objects
+// generated into a package have unique names.  A namer is safe for concurrent
+// use, but objects are only named deterministically when named in order.
+type Namer struct {
+	pattern *template.Template
+
+	mu   sync.Mutex
+	used map[string]bool
+}
+
+// New returns a namer which names objects with a pattern, which is a go
+// template, with sprig functions, of the naming fields of an object.  Words are
+// separated by any character which is not a letter or a digit, and joined in
+// camel case, so that the default pattern of {{ .Kind }}-{{ .Name }} names a
+// ConfigMap named web-config configMapWebConfig.  An empty pattern is the
+// default pattern.
+func New(pattern string) (*Namer, error) {
+	if pattern == "" {
+		pattern = DefaultPattern
+	}
+
+	parsed, err := template.New("name").Funcs(sprig.TxtFuncMap()).Option("missingkey=error").Parse(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%w; %s %s", err, ErrInvalidPattern, pattern)
+	}
+
+	// references to fields which do not exist are only reported on execution
+	if err := parsed.Execute(io.Discard, &Fields{}); err != nil {
+		return nil, fmt.Errorf("%w; %s %s", err, ErrInvalidPattern, pattern)
+	}
+
+	return &Namer{pattern: parsed, used: map[string]bool{}}, nil
+}
+
+// Name returns the unique name of the variable, or constructor function when
+// constructor is set, of one of a number of objects.  A given name names a
+// single object, or prefixes the names of multiple objects, otherwise objects
+// are named with the pattern.
+func (namer *Namer) Name(object Object, given string, objects int, constructor bool) (string, error) {
+	words := given
+
+	if given == "" || objects > 1 {
+		var buf bytes.Buffer
+		if err := namer.pattern.Execute(&buf, FieldsOf(object)); err != nil {
+			return "", fmt.Errorf("%w; %s for object %s/%s", err, ErrInvalidPattern, object.GetNamespace(), object.GetName())
+		}
+
+		words = given + "-" + buf.String()
+	}
+
+	return namer.unique(identifier(words, constructor)), nil
+}
+
+// unique returns a name which has not yet been given, by suffixing it with a
+// number if needed, and records it as given.
+func (namer *Namer) unique(name string) string {
+	namer.mu.Lock()
+	defer namer.mu.Unlock()
+
+	candidate := name
+
+	for i := 2; namer.used[candidate]; i++ {
+		candidate = name + strconv.Itoa(i)
+	}
+
+	namer.used[candidate] = true
+
+	return candidate
+}
+
+// identifier returns the words of a name as a go identifier in camel case,
+// exported with a New prefix for a constructor function.
+func identifier(words string, constructor bool) string {
+	// characters other than letters and digits separate words
+	sanitised := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+
+		return '-'
+	}, words)
+
+	name := strcase.ToCamel(sanitised)
+
+	if constructor {
+		return "New" + name
+	}
+
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = strcase.ToCamel(fallbackName) + name
+	}
+
+	name = strcase.ToLowerCamel(name)
+
+	if token.IsKeyword(name) || reserved[name] {
+		name += strcase.ToCamel(fallbackName)
+	}
+
+	return name
+}
@@ -0,0 +1,179 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package naming
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// newObject returns an unstructured object of a kind with a namespace and name.
+func newObject(apiVersion, kind, namespace, name string) *unstructured.Unstructured {
+	object := &unstructured.Unstructured{}
+	object.SetAPIVersion(apiVersion)
+	object.SetKind(kind)
+	object.SetNamespace(namespace)
+	object.SetName(name)
+
+	return object
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		pattern string
+		wantErr bool
+	}{
+		{
+			name:    "ensure an empty pattern is the default pattern",
+			pattern: "",
+		},
+		{
+			name:    "ensure a pattern may use sprig functions",
+			pattern: "{{ .Namespace | default \"cluster\" }}-{{ .Kind | lower }}",
+		},
+		{
+			name:    "ensure a pattern which does not parse is rejected",
+			pattern: "{{ .Kind ",
+			wantErr: true,
+		},
+		{
+			name:    "ensure a pattern referencing an unknown field is rejected",
+			pattern: "{{ .Labels }}",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := New(tt.pattern)
+			if tt.wantErr {
+				assert.ErrorContains(t, err, ErrInvalidPattern.Error())
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestNamer_Name(t *testing.T) {
+	t.Parallel()
+
+	type name struct {
+		object      *unstructured.Unstructured
+		given       string
+		objects     int
+		constructor bool
+	}
+
+	tests := []struct {
+		name    string
+		pattern string
+		names   []name
+		want    []string
+	}{
+		{
+			name: "ensure objects are named after their kind and name by default",
+			names: []name{
+				{object: newObject("v1", "ConfigMap", "default", "web-config"), objects: 2},
+				{object: newObject("apps/v1", "Deployment", "default", "web"), objects: 2},
+			},
+			want: []string{"configMapWebConfig", "deploymentWeb"},
+		},
+		{
+			name: "ensure colliding names are suffixed in order",
+			names: []name{
+				{object: newObject("v1", "ConfigMap", "dev", "config"), objects: 3},
+				{object: newObject("v1", "ConfigMap", "prod", "config"), objects: 3},
+				{object: newObject("v1", "ConfigMap", "test", "config"), objects: 3},
+			},
+			want: []string{"configMapConfig", "configMapConfig2", "configMapConfig3"},
+		},
+		{
+			name:    "ensure a pattern may distinguish objects by namespace",
+			pattern: "{{ .Namespace }}-{{ .Kind }}-{{ .Name }}",
+			names: []name{
+				{object: newObject("v1", "ConfigMap", "dev", "config"), objects: 2},
+				{object: newObject("v1", "ConfigMap", "prod", "config"), objects: 2},
+			},
+			want: []string{"devConfigMapConfig", "prodConfigMapConfig"},
+		},
+		{
+			name:    "ensure keywords and reserved names are suffixed",
+			pattern: "{{ .Name }}",
+			names: []name{
+				{object: newObject("v1", "ConfigMap", "", "type"), objects: 3},
+				{object: newObject("v1", "ConfigMap", "", "string"), objects: 3},
+				{object: newObject("v1", "ConfigMap", "", "unstructured"), objects: 3},
+			},
+			want: []string{"typeObject", "stringObject", "unstructuredObject"},
+		},
+		{
+			name:    "ensure names starting with a digit or without words are prefixed",
+			pattern: "{{ .Name }}",
+			names: []name{
+				{object: newObject("v1", "ConfigMap", "", "1st.config"), objects: 2},
+				{object: newObject("v1", "ConfigMap", "", ""), objects: 2},
+			},
+			want: []string{"object1StConfig", "object"},
+		},
+		{
+			name: "ensure a given name names a single object",
+			names: []name{
+				{object: newObject("v1", "ConfigMap", "", "web"), given: "webConfig", objects: 1},
+			},
+			want: []string{"webConfig"},
+		},
+		{
+			name: "ensure a given name prefixes the names of multiple objects",
+			names: []name{
+				{object: newObject("v1", "ConfigMap", "", "web"), given: "resource", objects: 2},
+				{object: newObject("v1", "Service", "", "web"), given: "resource", objects: 2},
+			},
+			want: []string{"resourceConfigMapWeb", "resourceServiceWeb"},
+		},
+		{
+			name: "ensure constructors are exported with a New prefix",
+			names: []name{
+				{object: newObject("v1", "ConfigMap", "", "web"), objects: 2, constructor: true},
+				{object: newObject("v1", "ConfigMap", "", "1st"), objects: 2, constructor: true},
+			},
+			want: []string{"NewConfigMapWeb", "NewConfigMap1St"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			namer, err := New(tt.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make([]string, 0, len(tt.names))
+
+			for _, n := range tt.names {
+				name, err := namer.Name(n.object, n.given, n.objects, n.constructor)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				got = append(got, name)
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -5,6 +5,7 @@ package rbac
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"sort"
 	"strings"
@@ -20,8 +21,8 @@ import (
 	"sigs.k8s.io/controller-tools/pkg/rbac"
 	"sigs.k8s.io/kubebuilder/v3/pkg/model/resource"
 
-	"github.com/nukleros/gener8s/internal/options"
 	"github.com/nukleros/gener8s/pkg/generate/code"
+	"github.com/nukleros/gener8s/pkg/generate/naming"
 	"github.com/nukleros/gener8s/pkg/manifests"
 )
 
@@ -30,6 +31,43 @@ const (
 	kubebuilderPrefix = "// +kubebuilder:rbac"
 )
 
+// Options represents the options for generating the rbac needed to manage the
+// objects of a set of manifests.
+type Options struct {
+	// RoleName is the name of the role, and cluster role, of the rbac.
+	RoleName string
+
+	// Verbs are the verbs of the rules for each object, which default to
+	// DefaultResourceVerbs when empty.
+	Verbs []string
+
+	// UseResourceNames determines if the rules are locked down to the names of
+	// the objects.
+	UseResourceNames bool
+
+	// VariableName names a single role, or prefixes the names of multiple roles,
+	// of the go form of the rbac.
+	VariableName string
+
+	// NamePattern is the pattern which the roles of the go form of the rbac are
+	// named with.  See naming.New for the form of the pattern.
+	NamePattern string
+
+	// Namer names the roles of the go form of the rbac, and ensures that all
+	// names are unique.  A namer may be shared by several calls which generate
+	// rbac into the same package.  When nil, roles are named with NamePattern.
+	Namer *naming.Namer
+}
+
+// verbs returns the verbs of the rules for each object.
+func (options *Options) verbs() []string {
+	if len(options.Verbs) == 0 {
+		return DefaultResourceVerbs()
+	}
+
+	return options.Verbs
+}
+
 // rbacWorkloadProcessor is an interface which implements processing of rbac rules
 // for individual workloads (e.g. standalone, collection, component).
 type rbacWorkloadProcessor interface {
@@ -120,25 +158,51 @@ func removeDupAndSort(strs []string) []string {
 }
 
 // GenerateYAML will return the stdout form of rbac objects, given a set of input manifest, in YAML format.
-func GenerateYAML(files *manifests.Manifests, options *options.RBACOptions) (string, error) {
+// Generation stops with the error of the context when it is done, which is checked for each object.
+func GenerateYAML(ctx context.Context, files *manifests.Manifests, options *Options) (string, error) {
+	roles, err := roleObjects(ctx, files, options)
+	if err != nil {
+		return "", err
+	}
+
+	encoded := make([]string, 0, len(roles))
+
+	for _, role := range roles {
+		manifest, err := encodeRole(role)
+		if err != nil {
+			return "", err
+		}
+
+		encoded = append(encoded, manifest)
+	}
+
+	return strings.Join(encoded, "---\n"), nil
+}
+
+// roleObjects returns the roles, and cluster role, for a set of input manifests.
+func roleObjects(ctx context.Context, files *manifests.Manifests, options *Options) ([]client.Object, error) {
 	// this is a controller-gen rule, in which we will convert rules from this package into
 	rulesByNS := map[string][]*rbac.Rule{}
 
+	decoder := serializer.NewCodecFactory(scheme.Scheme).UniversalDecoder()
+
 	for _, manifest := range *files {
-		for _, resource := range manifest.ExtractManifests() {
+		for _, resource := range manifest.ExtractObjects() {
+			if err := ctx.Err(); err != nil {
+				return nil, fmt.Errorf("%w", err)
+			}
+
 			// decode manifest into unstructured data type
 			var manifestObject unstructured.Unstructured
 
-			decoder := serializer.NewCodecFactory(scheme.Scheme).UniversalDecoder()
-
-			if err := runtime.DecodeInto(decoder, []byte(resource), &manifestObject); err != nil {
-				return "", fmt.Errorf("%w; unable to decode object in manifest file %s", err, manifest.Filename)
+			if err := runtime.DecodeInto(decoder, []byte(resource.Content), &manifestObject); err != nil {
+				return nil, resource.Wrap(fmt.Errorf("%w; unable to decode object", err))
 			}
 
 			// determine the rbac rules for this resource
-			resourceRules, err := ForResource(&manifestObject, options.Verbs...)
+			resourceRules, err := ForResource(&manifestObject, options.verbs()...)
 			if err != nil {
-				return "", err
+				return nil, err
 			}
 
 			for _, this := range *resourceRules {
@@ -248,44 +312,44 @@ func GenerateYAML(files *manifests.Manifests, options *options.RBACOptions) (str
 		}
 	}
 
-	e := json.NewYAMLSerializer(json.DefaultMetaFactory, nil, nil)
-
-	var rbacString string
+	return roles, nil
+}
 
-	for i, role := range roles {
-		buf := new(bytes.Buffer)
+// encodeRole returns the yaml manifest of a role.
+func encodeRole(role client.Object) (string, error) {
+	e := json.NewYAMLSerializer(json.DefaultMetaFactory, nil, nil)
 
-		if err := e.Encode(role, buf); err != nil {
-			return "", fmt.Errorf("%w - error encoding role to string", err)
-		}
+	buf := new(bytes.Buffer)
 
-		if i == 0 {
-			rbacString = buf.String()
-		} else {
-			rbacString = fmt.Sprintf("%s---\n%s", rbacString, buf.String())
-		}
+	if err := e.Encode(role, buf); err != nil {
+		return "", fmt.Errorf("%w - error encoding role to string", err)
 	}
 
-	return rbacString, nil
+	return buf.String(), nil
 }
 
 // GenerateMarkers will return the stdout form of rbac objects as kubebuilder markers.
-func GenerateMarkers(files *manifests.Manifests, options *options.RBACOptions) (string, error) {
+// Generation stops with the error of the context when it is done, which is checked for each object.
+func GenerateMarkers(ctx context.Context, files *manifests.Manifests, options *Options) (string, error) {
 	var rbacString string
 
 	for _, manifest := range *files {
-		for _, resource := range manifest.ExtractManifests() {
+		for _, resource := range manifest.ExtractObjects() {
+			if err := ctx.Err(); err != nil {
+				return "", fmt.Errorf("%w", err)
+			}
+
 			// decode manifest into unstructured data type
 			var manifestObject unstructured.Unstructured
 
 			decoder := serializer.NewCodecFactory(scheme.Scheme).UniversalDecoder()
 
-			if err := runtime.DecodeInto(decoder, []byte(resource), &manifestObject); err != nil {
-				return "", fmt.Errorf("%w; unable to decode object in manifest file %s", err, manifest.Filename)
+			if err := runtime.DecodeInto(decoder, []byte(resource.Content), &manifestObject); err != nil {
+				return "", resource.Wrap(fmt.Errorf("%w; unable to decode object", err))
 			}
 
 			// determine the rbac rules for this resource
-			resourceRules, err := ForResource(&manifestObject, options.Verbs...)
+			resourceRules, err := ForResource(&manifestObject, options.verbs()...)
 			if err != nil {
 				return "", err
 			}
@@ -304,34 +368,50 @@ func GenerateMarkers(files *manifests.Manifests, options *options.RBACOptions) (
 }
 
 // GenerateCode will return the stdout form of rbac objects, given a set of input manifest, in go struct format.
-func GenerateCode(files *manifests.Manifests, options *options.RBACOptions) (string, error) {
-	var rbacString string
+// The variable name of the options names a single role, or prefixes the names of multiple roles, which are
+// otherwise named with the naming pattern of the options.  Generation stops with the error of the context
+// when it is done, which is checked for each object.
+func GenerateCode(ctx context.Context, files *manifests.Manifests, options *Options) (string, error) {
+	var rbacString strings.Builder
+
+	namer := options.Namer
+
+	if namer == nil {
+		var err error
+		if namer, err = naming.New(options.NamePattern); err != nil {
+			return "", fmt.Errorf("%w", err)
+		}
+	}
 
-	manifestData, err := GenerateYAML(files, options)
+	roles, err := roleObjects(ctx, files, options)
 	if err != nil {
-		return rbacString, fmt.Errorf("%w - error converting manifests to yaml", err)
+		return "", fmt.Errorf("%w - error converting manifests to yaml", err)
 	}
 
-	manifest := &manifests.Manifest{Content: []byte(manifestData)}
+	for i, role := range roles {
+		resource, err := encodeRole(role)
+		if err != nil {
+			return rbacString.String(), err
+		}
 
-	for i, resource := range manifest.ExtractManifests() {
-		if len(manifest.ExtractManifests()) > 1 {
-			options.VariableName = fmt.Sprintf("%s%d", options.VariableName, i)
+		name, err := namer.Name(role, options.VariableName, len(roles), false)
+		if err != nil {
+			return rbacString.String(), fmt.Errorf("%w", err)
 		}
 
-		asCode, err := code.Generate([]byte(resource), options.VariableName)
+		asCode, err := code.Generate([]byte(strings.TrimSpace(resource)), name)
 		if err != nil {
-			return rbacString, fmt.Errorf("%w - error generating code for yaml", err)
+			return rbacString.String(), fmt.Errorf("%w - error generating code for yaml", err)
 		}
 
-		if rbacString == "" {
-			rbacString = fmt.Sprintf("%s\n\n", asCode)
+		if i == 0 {
+			rbacString.WriteString(asCode + "\n\n")
 		} else {
-			rbacString = fmt.Sprintf("%s%s\n", rbacString, asCode)
+			rbacString.WriteString(asCode + "\n")
 		}
 	}
 
-	return rbacString, nil
+	return rbacString.String(), nil
 }
 
 // ForResource will return a set of rules for a particular kubernetes resource.  This includes
@@ -4,8 +4,13 @@
 package rbac
 
 import (
+	"context"
 	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/nukleros/gener8s/pkg/generate/naming"
+	"github.com/nukleros/gener8s/pkg/manifests"
 )
 
 func Test_getGroup(t *testing.T) {
@@ -260,3 +265,39 @@ func Test_valueFromInterface(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateCode_sharedNamer(t *testing.T) {
+	t.Parallel()
+
+	namer, err := naming.New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+
+	for _, content := range []string{
+		"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n  namespace: x\n",
+		"apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: b\n  namespace: x\n",
+	} {
+		manifest, err := manifests.FromReader(strings.NewReader(content), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := GenerateCode(context.Background(), &manifests.Manifests{manifest}, &Options{
+			RoleName:     "manager-role",
+			VariableName: "resourceObj",
+			Namer:        namer,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		names = append(names, strings.Fields(got)[1])
+	}
+
+	if !reflect.DeepEqual(names, []string{"resourceObj", "resourceObj2"}) {
+		t.Errorf("GenerateCode() names = %v, want unique names", names)
+	}
+}
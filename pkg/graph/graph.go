@@ -0,0 +1,332 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package graph
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+
+	"github.com/nukleros/gener8s/pkg/manifests"
+)
+
+var (
+	ErrUnknownFormat      = errors.New("unknown graph format")
+	ErrDanglingReferences = errors.New("objects reference objects which are not in the manifests")
+)
+
+// Format is the form in which a graph is written.
+type Format string
+
+const (
+	// FormatDOT writes a graph in the DOT language of graphviz.
+	FormatDOT Format = "dot"
+
+	// FormatJSON writes a graph as json, with a list of nodes and of edges.
+	FormatJSON Format = "json"
+)
+
+// Node represents an object of the manifests, or an object which is referenced
+// by them but is missing from them.
+type Node struct {
+	// ID identifies the object by its kind and API group, namespace and name.
+	ID string `json:"id"`
+
+	Group     string `json:"group,omitempty"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+
+	// Filename and Line are the position of the object within its manifest
+	// file, unless it is missing.
+	Filename string `json:"filename,omitempty"`
+	Line     int    `json:"line,omitempty"`
+
+	// Missing determines if the object is referenced but is not in the
+	// manifests.  The name of a missing object referenced by a label selector is
+	// the selector.
+	Missing bool `json:"missing,omitempty"`
+}
+
+// Edge represents a reference from one object to another.
+type Edge struct {
+	// From and To are the IDs of the referencing and referenced objects.
+	From string `json:"from"`
+	To   string `json:"to"`
+
+	// Via is the field of the referencing object which holds the reference,
+	// relative to its pod spec for references from a pod template.
+	Via string `json:"via"`
+}
+
+// Graph represents the references between the objects of a set of manifests.
+// Nodes are in the order of the objects within the manifests, followed by the
+// missing objects in the order in which they are first referenced.
+type Graph struct {
+	Nodes []*Node `json:"nodes"`
+	Edges []*Edge `json:"edges"`
+
+	nodes map[string]*Node
+	edges map[Edge]bool
+}
+
+// ID returns the ID of an object of a kind in an API group, in a namespace when
+// it is given, with a name.  The kind is qualified with the group, as with
+// kubectl (e.g. Deployment.apps/shop/web), unless it is the core group.
+func ID(kind, group, namespace, name string) string {
+	if namespace == "" {
+		return qualifiedKind(kind, group) + "/" + name
+	}
+
+	return qualifiedKind(kind, group) + "/" + namespace + "/" + name
+}
+
+// qualifiedKind returns a kind qualified with its API group, unless it is the
+// core group.
+func qualifiedKind(kind, group string) string {
+	if group == "" {
+		return kind
+	}
+
+	return kind + "." + group
+}
+
+// objectID returns the ID of an object.
+func objectID(object *unstructured.Unstructured) string {
+	return ID(object.GetKind(), object.GroupVersionKind().Group, object.GetNamespace(), object.GetName())
+}
+
+// Build builds the graph of the references between the objects of a set of
+// manifests.  References to objects which are not in the manifests are
+// references to missing nodes.  Objects which are defined more than once are
+// represented by their first definition.
+func Build(files *manifests.Manifests) (*Graph, error) {
+	graph := &Graph{
+		Nodes: []*Node{},
+		Edges: []*Edge{},
+		nodes: map[string]*Node{},
+		edges: map[Edge]bool{},
+	}
+
+	var objects []*unstructured.Unstructured
+
+	for _, resource := range files.ExtractObjects() {
+		object, err := decodeObject(resource)
+		if err != nil {
+			return nil, err
+		}
+
+		node := &Node{
+			ID:        objectID(object),
+			Group:     object.GroupVersionKind().Group,
+			Kind:      object.GetKind(),
+			Namespace: object.GetNamespace(),
+			Name:      object.GetName(),
+			Filename:  resource.Filename,
+			Line:      resource.Line,
+		}
+
+		if graph.nodes[node.ID] != nil {
+			continue
+		}
+
+		graph.addNode(node)
+
+		objects = append(objects, object)
+	}
+
+	for _, object := range objects {
+		from := objectID(object)
+
+		for _, ref := range references(object) {
+			if ref.Selector == nil {
+				graph.addEdge(from, graph.target(ref, ref.Name), ref.Via)
+
+				continue
+			}
+
+			graph.addSelectorEdges(from, ref, objects)
+		}
+	}
+
+	return graph, nil
+}
+
+// decodeObject returns the unstructured form of an object.
+func decodeObject(resource *manifests.Object) (*unstructured.Unstructured, error) {
+	content, err := yaml.YAMLToJSON([]byte(resource.Content))
+	if err != nil {
+		return nil, resource.Wrap(fmt.Errorf("failed to convert YAML to JSON: %w", err))
+	}
+
+	object := &unstructured.Unstructured{}
+	if err := object.UnmarshalJSON(content); err != nil {
+		return nil, resource.Wrap(fmt.Errorf("failed to unmarshal JSON into unstructured object: %w", err))
+	}
+
+	return object, nil
+}
+
+// addNode adds a node to the graph.
+func (graph *Graph) addNode(node *Node) {
+	graph.nodes[node.ID] = node
+	graph.Nodes = append(graph.Nodes, node)
+}
+
+// addEdge adds an edge to the graph, unless it has already been added.
+func (graph *Graph) addEdge(from, to, via string) {
+	edge := Edge{From: from, To: to, Via: via}
+	if graph.edges[edge] {
+		return
+	}
+
+	graph.edges[edge] = true
+	graph.Edges = append(graph.Edges, &edge)
+}
+
+// target returns the ID of the object of a reference with a name, adding a
+// missing node for it if it is not in the graph.
+func (graph *Graph) target(ref *reference, name string) string {
+	id := ID(ref.Kind, ref.Group, ref.Namespace, name)
+
+	if graph.nodes[id] == nil {
+		graph.addNode(&Node{ID: id, Group: ref.Group, Kind: ref.Kind, Namespace: ref.Namespace, Name: name, Missing: true})
+	}
+
+	return id
+}
+
+// addSelectorEdges adds an edge from an object to each object in the namespace
+// of a reference with pods which match its selector, or to a missing pod named
+// after the selector when there are none.
+func (graph *Graph) addSelectorEdges(from string, ref *reference, objects []*unstructured.Unstructured) {
+	selector := labels.SelectorFromSet(ref.Selector)
+	matched := false
+
+	for _, object := range objects {
+		podLabels, ok := podLabels(object)
+		if !ok || object.GetNamespace() != ref.Namespace || !selector.Matches(labels.Set(podLabels)) {
+			continue
+		}
+
+		graph.addEdge(from, objectID(object), ref.Via)
+
+		matched = true
+	}
+
+	if !matched {
+		graph.addEdge(from, graph.target(ref, selector.String()), ref.Via)
+	}
+}
+
+// Node returns the node with an ID, or nil if there is none.
+func (graph *Graph) Node(id string) *Node {
+	return graph.nodes[id]
+}
+
+// Dangling returns the edges which reference missing objects, in order.
+func (graph *Graph) Dangling() []*Edge {
+	var dangling []*Edge
+
+	for _, edge := range graph.Edges {
+		if graph.nodes[edge.To].Missing {
+			dangling = append(dangling, edge)
+		}
+	}
+
+	return dangling
+}
+
+// Summary returns a summary of the dangling references of the graph, each at
+// the position of the referencing object, or an empty string when there are
+// none.
+func (graph *Graph) Summary() string {
+	dangling := graph.Dangling()
+	if len(dangling) == 0 {
+		return ""
+	}
+
+	summary := fmt.Sprintf("found %d dangling reference(s):\n", len(dangling))
+
+	for _, edge := range dangling {
+		from, to := graph.nodes[edge.From], graph.nodes[edge.To]
+
+		summary = fmt.Sprintf("%s  %s:%d: %s references missing %s (%s)\n",
+			summary, from.Filename, from.Line, from.ID, to.ID, edge.Via)
+	}
+
+	return summary
+}
+
+// Write writes the graph to a writer in a format.
+func (graph *Graph) Write(w io.Writer, format Format) error {
+	switch format {
+	case FormatDOT:
+		return graph.WriteDOT(w)
+	case FormatJSON:
+		return graph.WriteJSON(w)
+	default:
+		return fmt.Errorf("%w %q; must be %s or %s", ErrUnknownFormat, format, FormatDOT, FormatJSON)
+	}
+}
+
+// WriteDOT writes the graph to a writer in the DOT language of graphviz.  Each
+// object is labeled with its kind and API group, namespace and name, and missing objects are
+// drawn dashed in red.
+func (graph *Graph) WriteDOT(w io.Writer) error {
+	var dot strings.Builder
+
+	dot.WriteString("digraph gener8s {\n\tnode [shape=box];\n")
+
+	for _, node := range graph.Nodes {
+		name := node.Name
+		if node.Namespace != "" {
+			name = node.Namespace + "/" + name
+		}
+
+		attributes := "label=" + strconv.Quote(qualifiedKind(node.Kind, node.Group)+"\n"+name)
+		if node.Missing {
+			attributes += ", style=dashed, color=red"
+		}
+
+		fmt.Fprintf(&dot, "\t%s [%s];\n", strconv.Quote(node.ID), attributes)
+	}
+
+	if len(graph.Edges) > 0 {
+		dot.WriteString("\n")
+	}
+
+	for _, edge := range graph.Edges {
+		fmt.Fprintf(&dot, "\t%s -> %s [label=%s];\n", strconv.Quote(edge.From), strconv.Quote(edge.To), strconv.Quote(edge.Via))
+	}
+
+	dot.WriteString("}\n")
+
+	if _, err := io.WriteString(w, dot.String()); err != nil {
+		return fmt.Errorf("%w; unable to write graph", err)
+	}
+
+	return nil
+}
+
+// WriteJSON writes the graph to a writer as indented json.
+func (graph *Graph) WriteJSON(w io.Writer) error {
+	content, err := json.MarshalIndent(graph, "", "  ")
+	if err != nil {
+		return fmt.Errorf("%w; unable to encode graph", err)
+	}
+
+	if _, err := w.Write(append(content, '\n')); err != nil {
+		return fmt.Errorf("%w; unable to write graph", err)
+	}
+
+	return nil
+}
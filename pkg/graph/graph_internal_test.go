@@ -0,0 +1,197 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nukleros/gener8s/pkg/manifests"
+)
+
+const testManifests = `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: web
+  namespace: shop
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: shop
+spec:
+  replicas: !!var:int32 replicas
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      serviceAccountName: web
+      containers:
+        - name: web
+          envFrom:
+            - configMapRef:
+                name: web-config
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: shop
+spec:
+  selector:
+    app: web
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: api
+  namespace: shop
+spec:
+  selector:
+    app: api
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: web
+  namespace: shop
+`
+
+// testGraph builds the graph of the test manifests.
+func testGraph(t *testing.T) *Graph {
+	t.Helper()
+
+	got, err := Build(&manifests.Manifests{{Filename: "web.yaml", Content: []byte(testManifests)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return got
+}
+
+func TestBuild(t *testing.T) {
+	t.Parallel()
+
+	got := testGraph(t)
+
+	assert.Equal(t, []*Node{
+		{ID: "ServiceAccount/shop/web", Kind: "ServiceAccount", Namespace: "shop", Name: "web", Filename: "web.yaml", Line: 1},
+		{ID: "Deployment.apps/shop/web", Group: "apps", Kind: "Deployment", Namespace: "shop", Name: "web", Filename: "web.yaml", Line: 7},
+		{ID: "Service/shop/web", Kind: "Service", Namespace: "shop", Name: "web", Filename: "web.yaml", Line: 26},
+		{ID: "Service/shop/api", Kind: "Service", Namespace: "shop", Name: "api", Filename: "web.yaml", Line: 35},
+		{ID: "ConfigMap/shop/web-config", Kind: "ConfigMap", Namespace: "shop", Name: "web-config", Missing: true},
+		{ID: "Pod/shop/app=api", Kind: "Pod", Namespace: "shop", Name: "app=api", Missing: true},
+	}, got.Nodes)
+
+	assert.Equal(t, []*Edge{
+		{From: "Deployment.apps/shop/web", To: "ServiceAccount/shop/web", Via: "serviceAccountName"},
+		{From: "Deployment.apps/shop/web", To: "ConfigMap/shop/web-config", Via: "containers.envFrom.configMapRef"},
+		{From: "Service/shop/web", To: "Deployment.apps/shop/web", Via: "selector"},
+		{From: "Service/shop/api", To: "Pod/shop/app=api", Via: "selector"},
+	}, got.Edges)
+
+	assert.Equal(t, []*Edge{got.Edges[1], got.Edges[3]}, got.Dangling())
+	assert.Equal(t, `found 2 dangling reference(s):
+  web.yaml:7: Deployment.apps/shop/web references missing ConfigMap/shop/web-config (containers.envFrom.configMapRef)
+  web.yaml:35: Service/shop/api references missing Pod/shop/app=api (selector)
+`, got.Summary())
+}
+
+func TestBuild_invalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := Build(&manifests.Manifests{{
+		Filename: "web.yaml",
+		Content:  []byte("kind: Service\nmetadata:\n  name: web\n---\nkind: ConfigMap\nmetadata: [\n"),
+	}})
+	assert.ErrorContains(t, err, "web.yaml:6:1:")
+}
+
+func TestGraph_Write(t *testing.T) {
+	t.Parallel()
+
+	graph := testGraph(t)
+
+	t.Run("ensure graphs are written in the dot language", func(t *testing.T) {
+		t.Parallel()
+
+		var got bytes.Buffer
+		if err := graph.Write(&got, FormatDOT); err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, `digraph gener8s {
+	node [shape=box];
+	"ServiceAccount/shop/web" [label="ServiceAccount\nshop/web"];
+	"Deployment.apps/shop/web" [label="Deployment.apps\nshop/web"];
+	"Service/shop/web" [label="Service\nshop/web"];
+	"Service/shop/api" [label="Service\nshop/api"];
+	"ConfigMap/shop/web-config" [label="ConfigMap\nshop/web-config", style=dashed, color=red];
+	"Pod/shop/app=api" [label="Pod\nshop/app=api", style=dashed, color=red];
+
+	"Deployment.apps/shop/web" -> "ServiceAccount/shop/web" [label="serviceAccountName"];
+	"Deployment.apps/shop/web" -> "ConfigMap/shop/web-config" [label="containers.envFrom.configMapRef"];
+	"Service/shop/web" -> "Deployment.apps/shop/web" [label="selector"];
+	"Service/shop/api" -> "Pod/shop/app=api" [label="selector"];
+}
+`, got.String())
+	})
+
+	t.Run("ensure graphs are written as json", func(t *testing.T) {
+		t.Parallel()
+
+		var got bytes.Buffer
+		if err := graph.Write(&got, FormatJSON); err != nil {
+			t.Fatal(err)
+		}
+
+		var decoded struct {
+			Nodes []*Node `json:"nodes"`
+			Edges []*Edge `json:"edges"`
+		}
+
+		if err := json.Unmarshal(got.Bytes(), &decoded); err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, graph.Nodes, decoded.Nodes)
+		assert.Equal(t, graph.Edges, decoded.Edges)
+	})
+
+	t.Run("ensure unknown formats are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		assert.ErrorIs(t, graph.Write(&bytes.Buffer{}, "yaml"), ErrUnknownFormat)
+	})
+}
+
+func TestBuild_groups(t *testing.T) {
+	t.Parallel()
+
+	content := `apiVersion: acme.com/v1
+kind: Database
+metadata:
+  name: web
+---
+apiVersion: other.io/v1
+kind: Database
+metadata:
+  name: web
+`
+
+	got, err := Build(&manifests.Manifests{{Filename: "db.yaml", Content: []byte(content)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if assert.Len(t, got.Nodes, 2) {
+		assert.Equal(t, "Database.acme.com/web", got.Nodes[0].ID)
+		assert.Equal(t, "Database.other.io/web", got.Nodes[1].ID)
+	}
+}
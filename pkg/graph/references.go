@@ -0,0 +1,301 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package graph
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// defaultServiceAccount is the service account which exists in every namespace,
+// and which is therefore never missing.
+const defaultServiceAccount = "default"
+
+// API groups of the objects which are referenced other than by the core group.
+const (
+	rbacGroup       = "rbac.authorization.k8s.io"
+	networkingGroup = "networking.k8s.io"
+)
+
+// reference represents a reference from an object to another object, by its
+// kind and API group, namespace and name, or to the objects with pods matching a
+// selector.  References are to the core group unless a group is given.
+type reference struct {
+	Group     string
+	Kind      string
+	Namespace string
+	Name      string
+	Selector  map[string]string
+	Via       string
+}
+
+// references returns the references of an object to other objects, in the
+// order of its fields.  References of namespaced objects to namespaced objects
+// are within the namespace of the object unless a namespace is given.
+func references(object *unstructured.Unstructured) []*reference {
+	namespace := object.GetNamespace()
+
+	var refs []*reference
+
+	if spec, ok := podSpec(object); ok {
+		refs = append(refs, podSpecReferences(spec, namespace)...)
+	}
+
+	switch object.GetKind() {
+	case "RoleBinding", "ClusterRoleBinding":
+		refs = append(refs, bindingReferences(object.Object, namespace)...)
+	case "Service":
+		if selector := stringMap(object.Object, "spec", "selector"); len(selector) > 0 {
+			refs = append(refs, &reference{Kind: "Pod", Namespace: namespace, Selector: selector, Via: "selector"})
+		}
+	case "Ingress":
+		refs = append(refs, ingressReferences(object.Object, namespace)...)
+	case "MutatingWebhookConfiguration", "ValidatingWebhookConfiguration":
+		for _, webhook := range maps(object.Object, "webhooks") {
+			refs = appendService(refs, webhook, "webhooks.clientConfig.service", "clientConfig", "service")
+		}
+	case "CustomResourceDefinition":
+		refs = appendService(refs, object.Object, "conversion.webhook.clientConfig.service",
+			"spec", "conversion", "webhook", "clientConfig", "service")
+	case "APIService":
+		refs = appendService(refs, object.Object, "service", "spec", "service")
+	}
+
+	return refs
+}
+
+// podSpec returns the pod spec of an object, which is either a pod or has a pod
+// template.
+func podSpec(object *unstructured.Unstructured) (map[string]interface{}, bool) {
+	fields := podTemplateFields(object)
+
+	spec, ok, _ := unstructured.NestedMap(object.Object, append(fields, "spec")...)
+
+	return spec, ok
+}
+
+// podLabels returns the labels of the pods of an object, which is either a pod
+// or has a pod template.
+func podLabels(object *unstructured.Unstructured) (map[string]string, bool) {
+	fields := podTemplateFields(object)
+
+	if _, ok := podSpec(object); !ok {
+		return nil, false
+	}
+
+	return stringMap(object.Object, append(fields, "metadata", "labels")...), true
+}
+
+// podTemplateFields returns the path of the fields of the pod template of an
+// object, which is empty for a pod.  Objects other than pods and cron jobs have
+// a pod template at spec.template when they have one at all.
+func podTemplateFields(object *unstructured.Unstructured) []string {
+	switch object.GetKind() {
+	case "Pod":
+		return []string{}
+	case "PodTemplate":
+		return []string{"template"}
+	case "CronJob":
+		return []string{"spec", "jobTemplate", "spec", "template"}
+	default:
+		return []string{"spec", "template"}
+	}
+}
+
+// podSpecReferences returns the references of a pod spec to service accounts,
+// secrets, config maps and persistent volume claims.  Optional references to
+// secrets and config maps, which need not exist, are skipped.
+func podSpecReferences(spec map[string]interface{}, namespace string) []*reference {
+	var refs []*reference
+
+	add := func(kind, name, via string) {
+		if name != "" {
+			refs = append(refs, &reference{Kind: kind, Namespace: namespace, Name: name, Via: via})
+		}
+	}
+
+	// addRequired adds the reference of the source at the fields of an object,
+	// which names the referenced object with a field, unless it is optional
+	addRequired := func(kind string, object map[string]interface{}, fields []string, nameField, via string) {
+		source := mapAt(object, fields...)
+
+		if optional, _, _ := unstructured.NestedBool(source, "optional"); !optional {
+			add(kind, str(source, nameField), via)
+		}
+	}
+
+	serviceAccount := str(spec, "serviceAccountName")
+	if serviceAccount == "" {
+		serviceAccount = str(spec, "serviceAccount")
+	}
+
+	if serviceAccount != defaultServiceAccount {
+		add("ServiceAccount", serviceAccount, "serviceAccountName")
+	}
+
+	for _, secret := range maps(spec, "imagePullSecrets") {
+		add("Secret", str(secret, "name"), "imagePullSecrets")
+	}
+
+	for _, volume := range maps(spec, "volumes") {
+		addRequired("ConfigMap", volume, []string{"configMap"}, "name", "volumes.configMap")
+		addRequired("Secret", volume, []string{"secret"}, "secretName", "volumes.secret")
+		add("PersistentVolumeClaim", str(volume, "persistentVolumeClaim", "claimName"), "volumes.persistentVolumeClaim")
+
+		for _, source := range maps(volume, "projected", "sources") {
+			addRequired("ConfigMap", source, []string{"configMap"}, "name", "volumes.projected.configMap")
+			addRequired("Secret", source, []string{"secret"}, "name", "volumes.projected.secret")
+		}
+	}
+
+	for _, containers := range []string{"initContainers", "containers", "ephemeralContainers"} {
+		for _, container := range maps(spec, containers) {
+			for _, envFrom := range maps(container, "envFrom") {
+				addRequired("ConfigMap", envFrom, []string{"configMapRef"}, "name", containers+".envFrom.configMapRef")
+				addRequired("Secret", envFrom, []string{"secretRef"}, "name", containers+".envFrom.secretRef")
+			}
+
+			for _, env := range maps(container, "env") {
+				addRequired("ConfigMap", env, []string{"valueFrom", "configMapKeyRef"}, "name", containers+".env.configMapKeyRef")
+				addRequired("Secret", env, []string{"valueFrom", "secretKeyRef"}, "name", containers+".env.secretKeyRef")
+			}
+		}
+	}
+
+	return refs
+}
+
+// bindingReferences returns the references of a role binding, or cluster role
+// binding, to its role and to the service accounts it binds the role to.
+func bindingReferences(binding map[string]interface{}, namespace string) []*reference {
+	var refs []*reference
+
+	if kind, name := str(binding, "roleRef", "kind"), str(binding, "roleRef", "name"); name != "" {
+		roleNamespace := namespace
+		if kind == "ClusterRole" {
+			roleNamespace = ""
+		}
+
+		group := str(binding, "roleRef", "apiGroup")
+		if group == "" {
+			group = rbacGroup
+		}
+
+		refs = append(refs, &reference{Group: group, Kind: kind, Namespace: roleNamespace, Name: name, Via: "roleRef"})
+	}
+
+	for _, subject := range maps(binding, "subjects") {
+		if str(subject, "kind") != "ServiceAccount" || str(subject, "name") == defaultServiceAccount {
+			continue
+		}
+
+		subjectNamespace := str(subject, "namespace")
+		if subjectNamespace == "" {
+			subjectNamespace = namespace
+		}
+
+		refs = append(refs, &reference{
+			Kind:      "ServiceAccount",
+			Namespace: subjectNamespace,
+			Name:      str(subject, "name"),
+			Via:       "subjects",
+		})
+	}
+
+	return refs
+}
+
+// ingressReferences returns the references of an ingress to its ingress class,
+// to the services of its backends and to the secrets of its tls certificates,
+// for both the networking.k8s.io/v1 and the earlier beta forms of backends.
+func ingressReferences(ingress map[string]interface{}, namespace string) []*reference {
+	var refs []*reference
+
+	add := func(kind, name, via string) {
+		if name != "" {
+			refs = append(refs, &reference{Kind: kind, Namespace: namespace, Name: name, Via: via})
+		}
+	}
+
+	if class := str(ingress, "spec", "ingressClassName"); class != "" {
+		refs = append(refs, &reference{Group: networkingGroup, Kind: "IngressClass", Name: class, Via: "ingressClassName"})
+	}
+
+	backends := []map[string]interface{}{
+		mapAt(ingress, "spec", "defaultBackend"),
+		mapAt(ingress, "spec", "backend"),
+	}
+
+	for _, rule := range maps(ingress, "spec", "rules") {
+		for _, path := range maps(rule, "http", "paths") {
+			backends = append(backends, mapAt(path, "backend"))
+		}
+	}
+
+	for _, backend := range backends {
+		service := str(backend, "service", "name")
+		if service == "" {
+			service = str(backend, "serviceName")
+		}
+
+		add("Service", service, "backend")
+	}
+
+	for _, tls := range maps(ingress, "spec", "tls") {
+		add("Secret", str(tls, "secretName"), "tls")
+	}
+
+	return refs
+}
+
+// appendService appends the reference to a service, by its namespace and name,
+// at the fields of an object, if there is one.
+func appendService(refs []*reference, object map[string]interface{}, via string, fields ...string) []*reference {
+	service := mapAt(object, fields...)
+
+	name := str(service, "name")
+	if name == "" {
+		return refs
+	}
+
+	return append(refs, &reference{Kind: "Service", Namespace: str(service, "namespace"), Name: name, Via: via})
+}
+
+// str returns the string at the fields of an object, or an empty string if there
+// is none.
+func str(object map[string]interface{}, fields ...string) string {
+	value, _, _ := unstructured.NestedString(object, fields...)
+
+	return value
+}
+
+// mapAt returns the map at the fields of an object, or nil if there is none.
+func mapAt(object map[string]interface{}, fields ...string) map[string]interface{} {
+	value, _, _ := unstructured.NestedMap(object, fields...)
+
+	return value
+}
+
+// stringMap returns the map of strings at the fields of an object, or nil if
+// there is none.
+func stringMap(object map[string]interface{}, fields ...string) map[string]string {
+	value, _, _ := unstructured.NestedStringMap(object, fields...)
+
+	return value
+}
+
+// maps returns the maps of the list at the fields of an object, skipping any
+// items which are not maps.
+func maps(object map[string]interface{}, fields ...string) []map[string]interface{} {
+	list, _, _ := unstructured.NestedSlice(object, fields...)
+
+	var items []map[string]interface{}
+
+	for _, item := range list {
+		if m, ok := item.(map[string]interface{}); ok {
+			items = append(items, m)
+		}
+	}
+
+	return items
+}
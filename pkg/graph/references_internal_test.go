@@ -0,0 +1,257 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package graph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nukleros/gener8s/pkg/manifests"
+)
+
+func Test_references(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		want    []*reference
+	}{
+		{
+			name: "ensure pod templates reference service accounts, secrets, config maps and claims",
+			content: `kind: Deployment
+metadata:
+  name: web
+  namespace: shop
+spec:
+  template:
+    spec:
+      serviceAccountName: web
+      imagePullSecrets:
+        - name: registry
+      volumes:
+        - configMap:
+            name: web-config
+        - secret:
+            secretName: web-tls
+        - persistentVolumeClaim:
+            claimName: data
+        - projected:
+            sources:
+              - configMap:
+                  name: projected
+      initContainers:
+        - envFrom:
+            - secretRef:
+                name: init
+      containers:
+        - env:
+            - valueFrom:
+                configMapKeyRef:
+                  name: env
+`,
+			want: []*reference{
+				{Kind: "ServiceAccount", Namespace: "shop", Name: "web", Via: "serviceAccountName"},
+				{Kind: "Secret", Namespace: "shop", Name: "registry", Via: "imagePullSecrets"},
+				{Kind: "ConfigMap", Namespace: "shop", Name: "web-config", Via: "volumes.configMap"},
+				{Kind: "Secret", Namespace: "shop", Name: "web-tls", Via: "volumes.secret"},
+				{Kind: "PersistentVolumeClaim", Namespace: "shop", Name: "data", Via: "volumes.persistentVolumeClaim"},
+				{Kind: "ConfigMap", Namespace: "shop", Name: "projected", Via: "volumes.projected.configMap"},
+				{Kind: "Secret", Namespace: "shop", Name: "init", Via: "initContainers.envFrom.secretRef"},
+				{Kind: "ConfigMap", Namespace: "shop", Name: "env", Via: "containers.env.configMapKeyRef"},
+			},
+		},
+		{
+			name: "ensure optional secrets and config maps are not referenced",
+			content: `kind: Deployment
+metadata:
+  name: web
+  namespace: shop
+spec:
+  template:
+    spec:
+      volumes:
+        - secret:
+            secretName: web-tls
+            optional: true
+        - projected:
+            sources:
+              - configMap:
+                  name: projected
+                  optional: true
+      containers:
+        - envFrom:
+            - configMapRef:
+                name: overrides
+                optional: true
+            - secretRef:
+                name: credentials
+                optional: false
+          env:
+            - valueFrom:
+                secretKeyRef:
+                  name: token
+                  optional: true
+`,
+			want: []*reference{
+				{Kind: "Secret", Namespace: "shop", Name: "credentials", Via: "containers.envFrom.secretRef"},
+			},
+		},
+		{
+			name: "ensure the pod templates of cron jobs and the specs of pods are referenced",
+			content: `kind: CronJob
+metadata:
+  name: backup
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          serviceAccount: backup
+---
+kind: Pod
+metadata:
+  name: debug
+spec:
+  serviceAccountName: default
+  volumes:
+    - secret:
+        secretName: debug
+`,
+			want: []*reference{
+				{Kind: "ServiceAccount", Name: "backup", Via: "serviceAccountName"},
+				{Kind: "Secret", Name: "debug", Via: "volumes.secret"},
+			},
+		},
+		{
+			name: "ensure bindings reference their roles and service account subjects",
+			content: `kind: RoleBinding
+metadata:
+  name: web
+  namespace: shop
+roleRef:
+  kind: ClusterRole
+  name: view
+subjects:
+  - kind: ServiceAccount
+    name: web
+  - kind: ServiceAccount
+    name: monitor
+    namespace: monitoring
+  - kind: User
+    name: jane
+`,
+			want: []*reference{
+				{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole", Name: "view", Via: "roleRef"},
+				{Kind: "ServiceAccount", Namespace: "shop", Name: "web", Via: "subjects"},
+				{Kind: "ServiceAccount", Namespace: "monitoring", Name: "monitor", Via: "subjects"},
+			},
+		},
+		{
+			name: "ensure services reference the pods matching their selector",
+			content: `kind: Service
+metadata:
+  name: web
+  namespace: shop
+spec:
+  selector:
+    app: web
+`,
+			want: []*reference{
+				{Kind: "Pod", Namespace: "shop", Selector: map[string]string{"app": "web"}, Via: "selector"},
+			},
+		},
+		{
+			name: "ensure ingresses reference their class, backends and certificates",
+			content: `kind: Ingress
+metadata:
+  name: web
+  namespace: shop
+spec:
+  ingressClassName: nginx
+  defaultBackend:
+    service:
+      name: default
+  rules:
+    - http:
+        paths:
+          - backend:
+              service:
+                name: web
+          - backend:
+              serviceName: legacy
+  tls:
+    - secretName: web-tls
+`,
+			want: []*reference{
+				{Group: "networking.k8s.io", Kind: "IngressClass", Name: "nginx", Via: "ingressClassName"},
+				{Kind: "Service", Namespace: "shop", Name: "default", Via: "backend"},
+				{Kind: "Service", Namespace: "shop", Name: "web", Via: "backend"},
+				{Kind: "Service", Namespace: "shop", Name: "legacy", Via: "backend"},
+				{Kind: "Secret", Namespace: "shop", Name: "web-tls", Via: "tls"},
+			},
+		},
+		{
+			name: "ensure webhooks, conversion webhooks and api services reference their services",
+			content: `kind: ValidatingWebhookConfiguration
+metadata:
+  name: web
+webhooks:
+  - clientConfig:
+      service:
+        name: webhook
+        namespace: system
+  - clientConfig:
+      url: https://example.com
+---
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  conversion:
+    webhook:
+      clientConfig:
+        service:
+          name: conversion
+          namespace: system
+---
+kind: APIService
+metadata:
+  name: v1.metrics.example.com
+spec:
+  service:
+    name: metrics
+    namespace: system
+`,
+			want: []*reference{
+				{Kind: "Service", Namespace: "system", Name: "webhook", Via: "webhooks.clientConfig.service"},
+				{Kind: "Service", Namespace: "system", Name: "conversion", Via: "conversion.webhook.clientConfig.service"},
+				{Kind: "Service", Namespace: "system", Name: "metrics", Via: "service"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			manifest := &manifests.Manifest{Filename: "test.yaml", Content: []byte(tt.content)}
+
+			var got []*reference
+
+			for _, resource := range manifest.ExtractObjects() {
+				object, err := decodeObject(resource)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				got = append(got, references(object)...)
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
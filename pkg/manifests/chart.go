@@ -0,0 +1,104 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package manifests
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+)
+
+var ErrProcessChart = errors.New("error processing chart")
+
+const (
+	DefaultReleaseName      = "release-name"
+	DefaultReleaseNamespace = "default"
+)
+
+// FromChart renders a local helm chart, equivalent to `helm template`, and
+// returns a manifest for each of the rendered templates as well as any custom
+// resource definitions in the crds directory of the chart.  The chart is
+// rendered entirely in process, without access to a cluster or a repository.
+func FromChart(chartPath, releaseName, releaseNamespace string, values map[string]interface{}) (*Manifests, error) {
+	chart, err := loader.Load(chartPath)
+	if err != nil {
+		return &Manifests{}, fmt.Errorf("%w; %s %s", err, ErrProcessChart, chartPath)
+	}
+
+	if err := chartutil.ProcessDependencies(chart, values); err != nil {
+		return &Manifests{}, fmt.Errorf("%w; %s %s", err, ErrProcessChart, chartPath)
+	}
+
+	if releaseName == "" {
+		releaseName = DefaultReleaseName
+	}
+
+	if releaseNamespace == "" {
+		releaseNamespace = DefaultReleaseNamespace
+	}
+
+	renderValues, err := chartutil.ToRenderValues(chart, values, chartutil.ReleaseOptions{
+		Name:      releaseName,
+		Namespace: releaseNamespace,
+		Revision:  1,
+		IsInstall: true,
+	}, chartutil.DefaultCapabilities)
+	if err != nil {
+		return &Manifests{}, fmt.Errorf("%w; %s %s", err, ErrProcessChart, chartPath)
+	}
+
+	rendered, err := engine.Render(chart, renderValues)
+	if err != nil {
+		return &Manifests{}, fmt.Errorf("%w; %s %s", err, ErrProcessChart, chartPath)
+	}
+
+	var manifests Manifests
+
+	for _, crd := range chart.CRDObjects() {
+		manifests = append(manifests, &Manifest{
+			Content:          crd.File.Data,
+			Filename:         chartFilename(chartPath, chart.Name(), crd.Filename),
+			RelativeFilename: crd.Filename,
+		})
+	}
+
+	templateNames := make([]string, 0, len(rendered))
+	for name := range rendered {
+		templateNames = append(templateNames, name)
+	}
+
+	sort.Strings(templateNames)
+
+	for _, name := range templateNames {
+		content := rendered[name]
+
+		// skip partials, notes and templates which render to nothing
+		if strings.HasPrefix(filepath.Base(name), "_") ||
+			strings.HasSuffix(name, "NOTES.txt") ||
+			strings.TrimSpace(content) == "" {
+			continue
+		}
+
+		manifests = append(manifests, &Manifest{
+			Content:          []byte(content),
+			Filename:         chartFilename(chartPath, chart.Name(), name),
+			RelativeFilename: name,
+		})
+	}
+
+	return &manifests, nil
+}
+
+// chartFilename returns the path of a file of a chart, from its name as it is
+// rendered, which is prefixed with the name of the chart rather than the name of
+// the chart directory.
+func chartFilename(chartPath, chartName, name string) string {
+	return filepath.Join(chartPath, filepath.FromSlash(strings.TrimPrefix(filepath.ToSlash(name), chartName+"/")))
+}
@@ -0,0 +1,56 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package manifests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromChart(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	// the chart directory is not named after the chart
+	files := map[string]string{
+		"web-chart/Chart.yaml":              "apiVersion: v2\nname: web\nversion: 0.1.0\n",
+		"web-chart/values.yaml":             "replicas: 1\nservice:\n  enabled: false\n",
+		"web-chart/crds/crd.yaml":           "apiVersion: apiextensions.k8s.io/v1\nkind: CustomResourceDefinition\nmetadata:\n  name: webs.acme.com\n",
+		"web-chart/templates/_helpers.tpl":  "{{- define \"web.name\" -}}{{ .Release.Name }}-web{{- end -}}\n",
+		"web-chart/templates/NOTES.txt":     "installed {{ .Release.Name }}\n",
+		"web-chart/templates/service.yaml":  "{{- if .Values.service.enabled }}\napiVersion: v1\nkind: Service\n{{- end }}\n",
+		"web-chart/templates/workload.yaml": "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: {{ include \"web.name\" . }}\n  namespace: {{ .Release.Namespace }}\nspec:\n  replicas: {{ .Values.replicas }}\n",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := FromChart(filepath.Join(dir, "web-chart"), "prod", "apps", map[string]interface{}{"replicas": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if assert.Len(t, *got, 2) {
+		assert.Equal(t, filepath.Join(dir, "web-chart", "crds", "crd.yaml"), (*got)[0].Filename)
+		assert.Equal(t, filepath.Join(dir, "web-chart", "templates", "workload.yaml"), (*got)[1].Filename)
+		assert.Contains(t, string((*got)[1].Content), "name: prod-web")
+		assert.Contains(t, string((*got)[1].Content), "namespace: apps")
+		assert.Contains(t, string((*got)[1].Content), "replicas: 3")
+	}
+
+	_, err = FromChart(filepath.Join(dir, "missing"), "", "", nil)
+	assert.Error(t, err)
+}
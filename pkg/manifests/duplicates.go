@@ -0,0 +1,464 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package manifests
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+var (
+	ErrDuplicateObject        = errors.New("objects are defined more than once with different content")
+	ErrUnknownDuplicatePolicy = errors.New("unknown duplicate policy")
+)
+
+// DuplicatePolicy is the way in which objects which are defined more than once,
+// with the same API group, kind, namespace and name, are resolved.  Definitions
+// of different versions of the same group are definitions of the same object.
+type DuplicatePolicy string
+
+const (
+	// DuplicatesError fails when an object is defined more than once with
+	// different content.  Identical definitions are reduced to the first.
+	DuplicatesError DuplicatePolicy = "error"
+
+	// DuplicatesKeepFirst keeps the first definition of an object.
+	DuplicatesKeepFirst DuplicatePolicy = "keep-first"
+
+	// DuplicatesKeepLast keeps the last definition of an object, such as the
+	// definition of an overlay which follows its base.
+	DuplicatesKeepLast DuplicatePolicy = "keep-last"
+
+	// DuplicatesMerge merges the later definitions of an object onto the first,
+	// in order.  Objects of kinds known to Kubernetes are merged with the
+	// semantics of a strategic merge patch, such that lists of containers are
+	// merged by name, and objects of other kinds are merged field by field.
+	DuplicatesMerge DuplicatePolicy = "merge"
+)
+
+const (
+	reasonDuplicate = "duplicate of %s in %s"
+	reasonIdentical = "identical to %s in %s"
+	reasonMerged    = "merged into %s in %s"
+)
+
+// objectKey identifies an object by its API group, kind, namespace and name, as
+// an object is the same object whichever version of its group it is defined in.
+type objectKey struct {
+	Group     string
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+func (key objectKey) String() string {
+	kind, name := key.Kind, key.Name
+
+	if key.Group != "" {
+		kind = kind + "." + key.Group
+	}
+
+	if key.Namespace != "" {
+		name = key.Namespace + "/" + name
+	}
+
+	return kind + " " + name
+}
+
+// definition represents a single definition of an object within the manifests.
+type definition struct {
+	object   *Object
+	document *yaml.Node
+}
+
+// position returns the position of the definition as a filename and document.
+func (def *definition) position() string {
+	return fmt.Sprintf("%s (document %d)", def.object.Filename, def.object.Document)
+}
+
+// Deduplicate returns the manifests with the objects which are defined more than
+// once resolved by a policy, along with the documents which were skipped, each
+// with the position of the definition which was kept.  Manifests with no
+// remaining documents are removed.  Documents which cannot be decoded, such as
+// those containing templating, and those without a kind or name, are retained.
+func (manifests *Manifests) Deduplicate(policy DuplicatePolicy) (*Manifests, []Skipped, error) {
+	switch policy {
+	case "":
+		return manifests, nil, nil
+	case DuplicatesError, DuplicatesKeepFirst, DuplicatesKeepLast, DuplicatesMerge:
+	default:
+		return &Manifests{}, nil, fmt.Errorf("%w %q; must be one of %s, %s, %s or %s", ErrUnknownDuplicatePolicy,
+			policy, DuplicatesError, DuplicatesKeepFirst, DuplicatesKeepLast, DuplicatesMerge)
+	}
+
+	extracted := make([][]*Object, len(*manifests))
+	definitions := map[objectKey][]*definition{}
+
+	var keys []objectKey
+
+	for i, manifest := range *manifests {
+		extracted[i] = manifest.ExtractObjects()
+
+		for _, object := range extracted[i] {
+			key, document, ok := decodeDefinition(object)
+			if !ok {
+				continue
+			}
+
+			if len(definitions[key]) == 0 {
+				keys = append(keys, key)
+			}
+
+			definitions[key] = append(definitions[key], &definition{object: object, document: document})
+		}
+	}
+
+	dropped := map[*Object]string{}
+	merged := map[*Object]string{}
+
+	var conflicts []string
+
+	for _, key := range keys {
+		defs := definitions[key]
+		if len(defs) == 1 {
+			continue
+		}
+
+		switch policy {
+		case DuplicatesError:
+			if !identical(defs) {
+				conflicts = append(conflicts, conflict(key, defs))
+
+				continue
+			}
+
+			drop(dropped, reasonIdentical, key, defs[0], defs[1:])
+		case DuplicatesKeepFirst:
+			drop(dropped, reasonDuplicate, key, defs[0], defs[1:])
+		case DuplicatesKeepLast:
+			drop(dropped, reasonDuplicate, key, defs[len(defs)-1], defs[:len(defs)-1])
+		case DuplicatesMerge:
+			content, err := mergeDefinitions(key, defs)
+			if err != nil {
+				return &Manifests{}, nil, err
+			}
+
+			merged[defs[0].object] = content
+
+			drop(dropped, reasonMerged, key, defs[0], defs[1:])
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return &Manifests{}, nil, fmt.Errorf("%w; %s", ErrDuplicateObject, strings.Join(conflicts, "; "))
+	}
+
+	var kept Manifests
+
+	var skipped []Skipped
+
+	for i, manifest := range *manifests {
+		var selected []*Object
+
+		replaced := map[int]string{}
+
+		for _, object := range extracted[i] {
+			if reason, ok := dropped[object]; ok {
+				skipped = append(skipped, Skipped{Filename: manifest.Filename, Reason: reason, Document: object.Document})
+
+				continue
+			}
+
+			if content, ok := merged[object]; ok {
+				replaced[object.Document] = content
+			}
+
+			selected = append(selected, object)
+		}
+
+		if len(selected) > 0 {
+			kept = append(kept, manifest.withObjects(extracted[i], selected).withContent(replaced))
+		}
+	}
+
+	return &kept, skipped, nil
+}
+
+// decodeDefinition decodes the yaml document of an object, along with the key
+// which identifies it, if it can be decoded and has a kind and name.
+func decodeDefinition(object *Object) (objectKey, *yaml.Node, bool) {
+	var document yaml.Node
+	if err := yaml.Unmarshal([]byte(object.Content), &document); err != nil || len(document.Content) == 0 {
+		return objectKey{}, nil, false
+	}
+
+	root := document.Content[0]
+
+	key := objectKey{
+		Group:     apiGroup(scalarAt(root, "apiVersion")),
+		Kind:      scalarAt(root, "kind"),
+		Namespace: scalarAt(root, "metadata", "namespace"),
+		Name:      scalarAt(root, "metadata", "name"),
+	}
+
+	if key.Kind == "" || key.Name == "" {
+		return objectKey{}, nil, false
+	}
+
+	return key, &document, true
+}
+
+// apiGroup returns the API group of an apiVersion, which is empty for the core
+// group, or the apiVersion itself when it is not valid.
+func apiGroup(apiVersion string) string {
+	groupVersion, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return apiVersion
+	}
+
+	return groupVersion.Group
+}
+
+// scalarAt returns the value of the scalar at the fields of a mapping node, or an
+// empty string if there is none.  Tagged values, such as variables, are returned
+// as written.
+func scalarAt(node *yaml.Node, fields ...string) string {
+	for _, field := range fields {
+		if node = mappingValue(node, field); node == nil {
+			return ""
+		}
+	}
+
+	if node.Kind != yaml.ScalarNode {
+		return ""
+	}
+
+	return node.Value
+}
+
+// mappingValue returns the value of a key of a mapping node, or nil if it is not
+// a mapping node or does not have the key.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// identical determines if the definitions of an object all have the same
+// content, ignoring differences in formatting.
+func identical(defs []*definition) bool {
+	var first interface{}
+
+	firstErr := defs[0].document.Decode(&first)
+
+	for _, def := range defs[1:] {
+		if def.object.Content == defs[0].object.Content {
+			continue
+		}
+
+		var other interface{}
+		if err := def.document.Decode(&other); err != nil || firstErr != nil || !reflect.DeepEqual(first, other) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// conflict returns a description of an object with conflicting definitions, with
+// the position of each definition.
+func conflict(key objectKey, defs []*definition) string {
+	positions := make([]string, len(defs))
+	for i, def := range defs {
+		positions[i] = fmt.Sprintf("%s:%d", def.object.Filename, def.object.Line)
+	}
+
+	return fmt.Sprintf("%s in %s", key, strings.Join(positions, ", "))
+}
+
+// drop records the reason each of the dropped definitions of an object is
+// skipped, in favour of the kept definition.
+func drop(dropped map[*Object]string, reason string, key objectKey, kept *definition, defs []*definition) {
+	for _, def := range defs {
+		dropped[def.object] = fmt.Sprintf(reason, key, kept.position())
+	}
+}
+
+// mergeDefinitions merges the definitions of an object onto the first, in order,
+// and returns the yaml content of the merged object.  Tags and comments of the
+// definitions are retained.  Definitions are merged with the patch metadata of
+// the version of the first definition.
+func mergeDefinitions(key objectKey, defs []*definition) (string, error) {
+	var patchMeta strategicpatch.LookupPatchMeta
+
+	target := defs[0].document.Content[0]
+	gvk := schema.FromAPIVersionAndKind(scalarAt(target, "apiVersion"), key.Kind)
+
+	if typed, err := scheme.Scheme.New(gvk); err == nil {
+		if meta, err := strategicpatch.NewPatchMetaFromStruct(typed); err == nil {
+			patchMeta = meta
+		}
+	}
+
+	for _, def := range defs[1:] {
+		mergeNodes(target, def.document.Content[0], patchMeta)
+	}
+
+	var content bytes.Buffer
+
+	encoder := yaml.NewEncoder(&content)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(defs[0].document); err != nil {
+		return "", fmt.Errorf("%w; %s", defs[0].object.Wrap(err), ErrProcessManifest)
+	}
+
+	return strings.TrimSpace(content.String()), nil
+}
+
+// mergeNodes merges a yaml node onto another.  Mappings are merged key by key,
+// where a null value removes the key, and lists are replaced unless the patch
+// metadata of the field, if any, has the merge strategy, in which case lists of
+// objects are merged by their merge key and lists of values are combined.  Any
+// other value replaces the value it is merged onto.
+func mergeNodes(dst, src *yaml.Node, patchMeta strategicpatch.LookupPatchMeta) {
+	if dst.Kind != yaml.MappingNode || src.Kind != yaml.MappingNode {
+		*dst = *src
+
+		return
+	}
+
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+
+		existing := mappingValue(dst, key.Value)
+
+		switch {
+		case value.Kind == yaml.ScalarNode && value.Tag == "!!null":
+			removeKey(dst, key.Value)
+		case existing == nil:
+			dst.Content = append(dst.Content, key, value)
+		case value.Kind == yaml.MappingNode:
+			mergeNodes(existing, value, structPatchMeta(patchMeta, key.Value))
+		case value.Kind == yaml.SequenceNode:
+			mergeSequences(existing, value, patchMeta, key.Value)
+		default:
+			*existing = *value
+		}
+	}
+}
+
+// mergeSequences merges a list onto the list of a field, according to the patch
+// metadata of the field.
+func mergeSequences(dst, src *yaml.Node, patchMeta strategicpatch.LookupPatchMeta, field string) {
+	if patchMeta == nil || dst.Kind != yaml.SequenceNode {
+		*dst = *src
+
+		return
+	}
+
+	itemMeta, meta, err := patchMeta.LookupPatchMetadataForSlice(field)
+	if err != nil || !contains(meta.GetPatchStrategies(), "merge") {
+		*dst = *src
+
+		return
+	}
+
+	mergeKey := meta.GetPatchMergeKey()
+
+	for _, item := range src.Content {
+		match := matchingItem(dst, item, mergeKey)
+
+		switch {
+		case match == nil:
+			dst.Content = append(dst.Content, item)
+		case mergeKey != "":
+			mergeNodes(match, item, itemMeta)
+		}
+	}
+}
+
+// matchingItem returns the item of a list which matches an item, by the value of
+// the merge key for objects, or by value for scalars, or nil if there is none.
+func matchingItem(list, item *yaml.Node, mergeKey string) *yaml.Node {
+	for _, candidate := range list.Content {
+		if mergeKey == "" {
+			if candidate.Kind == yaml.ScalarNode && item.Kind == yaml.ScalarNode && candidate.Value == item.Value {
+				return candidate
+			}
+
+			continue
+		}
+
+		if value := scalarAt(item, mergeKey); value != "" && scalarAt(candidate, mergeKey) == value {
+			return candidate
+		}
+	}
+
+	return nil
+}
+
+// structPatchMeta returns the patch metadata of a field, or nil if there is none,
+// such as for a field which is a map rather than a struct.
+func structPatchMeta(patchMeta strategicpatch.LookupPatchMeta, field string) strategicpatch.LookupPatchMeta {
+	if patchMeta == nil {
+		return nil
+	}
+
+	fieldMeta, _, err := patchMeta.LookupPatchMetadataForStruct(field)
+	if err != nil {
+		return nil
+	}
+
+	return fieldMeta
+}
+
+// removeKey removes a key, and its value, from a mapping node.
+func removeKey(node *yaml.Node, key string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+
+			return
+		}
+	}
+}
+
+// withContent returns the manifest with the content of some of its documents,
+// by their position, replaced when extracting its objects, or the manifest
+// itself when there are none.
+func (manifest *Manifest) withContent(replaced map[int]string) *Manifest {
+	if len(replaced) == 0 {
+		return manifest
+	}
+
+	updated := *manifest
+	updated.replaced = map[int]string{}
+
+	for document, content := range manifest.replaced {
+		updated.replaced[document] = content
+	}
+
+	for document, content := range replaced {
+		updated.replaced[document] = content
+	}
+
+	return &updated
+}
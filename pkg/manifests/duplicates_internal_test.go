@@ -0,0 +1,221 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package manifests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifests_Deduplicate(t *testing.T) {
+	t.Parallel()
+
+	base := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: shop
+  labels:
+    app: web
+spec:
+  replicas: !!var:int32 replicas
+  template:
+    spec:
+      containers:
+        - name: web
+          image: web:1.0
+          env:
+            - name: MODE
+              value: base
+        - name: proxy
+          image: proxy:1.0`
+	overlay := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: shop
+  labels:
+    tier: frontend
+spec:
+  template:
+    spec:
+      containers:
+        - name: web
+          image: web:2.0
+          env:
+            - name: DEBUG
+              value: "true"`
+	service := "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n  namespace: shop"
+	crd := "apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: web\nspec:\n  sizes: [small]\n  color: red"
+	crdOverlay := "apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: web\nspec:\n  sizes: [large]\n  color: null"
+
+	files := Manifests{
+		{Filename: "base.yaml", Content: []byte(base + "\n---\n" + service + "\n---\n" + crd + "\n")},
+		{Filename: "overlay.yaml", Content: []byte(overlay + "\n---\n" + crdOverlay + "\n")},
+		{Filename: "copy.yaml", Content: []byte("apiVersion: v1\nkind: Service\nmetadata:\n  namespace: shop\n  name: web\n")},
+	}
+
+	tests := []struct {
+		name        string
+		policy      DuplicatePolicy
+		want        []string
+		wantSkipped []Skipped
+		wantErr     error
+	}{
+		{
+			name:   "ensure no policy retains all objects",
+			policy: "",
+			want: []string{
+				base, service, crd, overlay, crdOverlay,
+				"apiVersion: v1\nkind: Service\nmetadata:\n  namespace: shop\n  name: web",
+			},
+		},
+		{
+			name:    "ensure differing definitions are reported by the error policy",
+			policy:  DuplicatesError,
+			wantErr: ErrDuplicateObject,
+		},
+		{
+			name:    "ensure unknown policies return an error",
+			policy:  "keep-all",
+			wantErr: ErrUnknownDuplicatePolicy,
+		},
+		{
+			name:   "ensure the first definition is kept by the keep-first policy",
+			policy: DuplicatesKeepFirst,
+			want:   []string{base, service, crd},
+			wantSkipped: []Skipped{
+				{Filename: "overlay.yaml", Document: 1, Reason: "duplicate of Deployment.apps shop/web in base.yaml (document 1)"},
+				{Filename: "overlay.yaml", Document: 2, Reason: "duplicate of Widget.example.com web in base.yaml (document 3)"},
+				{Filename: "copy.yaml", Document: 1, Reason: "duplicate of Service shop/web in base.yaml (document 2)"},
+			},
+		},
+		{
+			name:   "ensure the last definition is kept by the keep-last policy",
+			policy: DuplicatesKeepLast,
+			want: []string{
+				overlay, crdOverlay,
+				"apiVersion: v1\nkind: Service\nmetadata:\n  namespace: shop\n  name: web",
+			},
+			wantSkipped: []Skipped{
+				{Filename: "base.yaml", Document: 1, Reason: "duplicate of Deployment.apps shop/web in overlay.yaml (document 1)"},
+				{Filename: "base.yaml", Document: 2, Reason: "duplicate of Service shop/web in copy.yaml (document 1)"},
+				{Filename: "base.yaml", Document: 3, Reason: "duplicate of Widget.example.com web in overlay.yaml (document 2)"},
+			},
+		},
+		{
+			name:   "ensure known kinds are strategically merged and other kinds are merged by field",
+			policy: DuplicatesMerge,
+			want: []string{
+				`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: shop
+  labels:
+    app: web
+    tier: frontend
+spec:
+  replicas: !!var:int32 replicas
+  template:
+    spec:
+      containers:
+        - name: web
+          image: web:2.0
+          env:
+            - name: MODE
+              value: base
+            - name: DEBUG
+              value: "true"
+        - name: proxy
+          image: proxy:1.0`,
+				service,
+				"apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: web\nspec:\n  sizes: [large]",
+			},
+			wantSkipped: []Skipped{
+				{Filename: "overlay.yaml", Document: 1, Reason: "merged into Deployment.apps shop/web in base.yaml (document 1)"},
+				{Filename: "overlay.yaml", Document: 2, Reason: "merged into Widget.example.com web in base.yaml (document 3)"},
+				{Filename: "copy.yaml", Document: 1, Reason: "merged into Service shop/web in base.yaml (document 2)"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, skipped, err := files.Deduplicate(tt.policy)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var objects []string
+			for _, object := range got.ExtractObjects() {
+				objects = append(objects, object.Content)
+			}
+
+			assert.Equal(t, tt.want, objects)
+			assert.Equal(t, tt.wantSkipped, skipped)
+		})
+	}
+}
+
+func TestManifests_Deduplicate_identical(t *testing.T) {
+	t.Parallel()
+
+	files := Manifests{
+		{Filename: "base.yaml", Content: []byte("kind: Namespace\nmetadata:\n  name: shop\n---\nkind: {{ .Kind }}\n")},
+		{Filename: "copy.yaml", Content: []byte("kind: Namespace\nmetadata: {name: shop}\n---\nkind: {{ .Kind }}\n")},
+	}
+
+	got, skipped, err := files.Deduplicate(DuplicatesError)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, got.ExtractObjects(), 3)
+	assert.Equal(t, []Skipped{
+		{Filename: "copy.yaml", Document: 1, Reason: "identical to Namespace shop in base.yaml (document 1)"},
+	}, skipped)
+}
+
+func TestManifests_Deduplicate_conflicts(t *testing.T) {
+	t.Parallel()
+
+	files := Manifests{
+		{Filename: "base.yaml", Content: []byte("kind: Namespace\nmetadata:\n  name: shop\n")},
+		{Filename: "overlay.yaml", Content: []byte("# overlay\nkind: Namespace\nmetadata:\n  name: shop\n  labels:\n    env: prod\n")},
+	}
+
+	_, _, err := files.Deduplicate(DuplicatesError)
+	assert.EqualError(t, err, ErrDuplicateObject.Error()+"; Namespace shop in base.yaml:1, overlay.yaml:1")
+}
+
+func TestManifests_Deduplicate_versions(t *testing.T) {
+	t.Parallel()
+
+	files := Manifests{
+		{Filename: "base.yaml", Content: []byte("apiVersion: autoscaling/v1\nkind: HorizontalPodAutoscaler\nmetadata:\n  name: web\n")},
+		{Filename: "overlay.yaml", Content: []byte("apiVersion: autoscaling/v2\nkind: HorizontalPodAutoscaler\nmetadata:\n  name: web\n")},
+		{Filename: "other.yaml", Content: []byte("apiVersion: example.com/v1\nkind: HorizontalPodAutoscaler\nmetadata:\n  name: web\n")},
+	}
+
+	got, skipped, err := files.Deduplicate(DuplicatesKeepLast)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, got.ExtractObjects(), 2)
+	assert.Equal(t, []Skipped{
+		{Filename: "base.yaml", Document: 1, Reason: "duplicate of HorizontalPodAutoscaler.autoscaling web in overlay.yaml (document 1)"},
+	}, skipped)
+}
@@ -0,0 +1,261 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package manifests
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Filter represents the criteria for selecting objects from manifests.  An object
+// is selected when it matches all of the criteria which are set, where it
+// matches a list of criteria when it matches any item of the list.
+type Filter struct {
+	// Kinds are the kinds of the selected objects, matched case insensitively.
+	Kinds []string
+
+	// Names are the names of the selected objects.
+	Names []string
+
+	// Namespaces are the namespaces of the selected objects.
+	Namespaces []string
+
+	// Selector is a label selector, in the full syntax supported by Kubernetes
+	// (e.g. "app=web,tier in (frontend,backend),!canary").
+	Selector string
+}
+
+// IsEmpty determines if the filter has no criteria, and therefore selects all
+// objects.
+func (filter *Filter) IsEmpty() bool {
+	return len(filter.Kinds) == 0 &&
+		len(filter.Names) == 0 &&
+		len(filter.Namespaces) == 0 &&
+		filter.Selector == ""
+}
+
+// filterObject represents the fields of an object which may be filtered on.  A
+// field is nil when its value is unknown, such as when it is set by a variable
+// or by templating which is not resolved.
+type filterObject struct {
+	Kind      *string
+	Name      *string
+	Namespace *string
+	Labels    labels.Set
+}
+
+// Filter returns the manifests with only the objects selected by the filter.
+// Manifests with no selected objects are removed.  Templating in the objects is
+// resolved by the values, if any, before they are matched, as it is when they
+// are generated.  Objects are not excluded by a criterion when the field it
+// matches is unknown, such as a name set by a variable, or when their document
+// cannot be decoded, as with SkipNonObjects.
+func (manifests *Manifests) Filter(filter *Filter, values map[string]interface{}) (*Manifests, error) {
+	if filter == nil || filter.IsEmpty() {
+		return manifests, nil
+	}
+
+	selector, err := labels.Parse(filter.Selector)
+	if err != nil {
+		return &Manifests{}, fmt.Errorf("%w; invalid label selector %s", err, filter.Selector)
+	}
+
+	var kept Manifests
+
+	for _, manifest := range *manifests {
+		var selected []*Object
+
+		objects := manifest.ExtractObjects()
+
+		for _, object := range objects {
+			fields, ok := decodeFilterObject(resolveTemplating(object.Content, values))
+			if !ok || filter.matches(fields, selector) {
+				selected = append(selected, object)
+			}
+		}
+
+		if len(selected) > 0 {
+			kept = append(kept, manifest.withObjects(objects, selected))
+		}
+	}
+
+	return &kept, nil
+}
+
+// resolveTemplating returns the content of an object with its templating
+// resolved by the values, or the content as it is when no values are given or
+// it cannot be resolved.
+func resolveTemplating(content string, values map[string]interface{}) string {
+	if values == nil {
+		return content
+	}
+
+	contentTemplate, err := template.New("object").Parse(content)
+	if err != nil {
+		return content
+	}
+
+	var resolved bytes.Buffer
+	if err := contentTemplate.Execute(&resolved, values); err != nil {
+		return content
+	}
+
+	return resolved.String()
+}
+
+// decodeFilterObject decodes the fields of an object which may be filtered on,
+// if its content can be decoded.
+func decodeFilterObject(content string) (*filterObject, bool) {
+	var document yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &document); err != nil {
+		return nil, false
+	}
+
+	if len(document.Content) == 0 {
+		return &filterObject{Kind: new(string), Name: new(string), Namespace: new(string), Labels: labels.Set{}}, true
+	}
+
+	root := document.Content[0]
+
+	fields := &filterObject{
+		Kind:      knownScalar(mappingValue(root, "kind")),
+		Name:      knownScalar(nodeAt(root, "metadata", "name")),
+		Namespace: knownScalar(nodeAt(root, "metadata", "namespace")),
+		Labels:    labels.Set{},
+	}
+
+	labelsNode := nodeAt(root, "metadata", "labels")
+	if labelsNode == nil {
+		return fields, true
+	}
+
+	if labelsNode.Kind != yaml.MappingNode || labelsNode.ShortTag() != "!!map" {
+		fields.Labels = nil
+
+		return fields, true
+	}
+
+	for i := 0; i+1 < len(labelsNode.Content); i += 2 {
+		key, value := knownScalar(labelsNode.Content[i]), knownScalar(labelsNode.Content[i+1])
+		if key == nil || value == nil {
+			fields.Labels = nil
+
+			return fields, true
+		}
+
+		fields.Labels[*key] = *value
+	}
+
+	return fields, true
+}
+
+// nodeAt returns the node at the fields of a mapping node, or nil if there is
+// none.
+func nodeAt(node *yaml.Node, fields ...string) *yaml.Node {
+	for _, field := range fields {
+		if node = mappingValue(node, field); node == nil {
+			return nil
+		}
+	}
+
+	return node
+}
+
+// knownScalar returns the value of a scalar node, which is empty when there is
+// no node, or nil when its value is unknown as it is not a plain value, such as
+// a value tagged as a variable, or contains templating.
+func knownScalar(node *yaml.Node) *string {
+	value := ""
+
+	if node == nil {
+		return &value
+	}
+
+	if node.Kind != yaml.ScalarNode || strings.Contains(node.Value, "{{") {
+		return nil
+	}
+
+	switch node.ShortTag() {
+	case "!!str", "!!int", "!!float", "!!bool", "!!null":
+	default:
+		return nil
+	}
+
+	if node.ShortTag() != "!!null" {
+		value = node.Value
+	}
+
+	return &value
+}
+
+// matches determines if an object is selected by the filter.  Criteria which
+// match an unknown field do not exclude the object.
+func (filter *Filter) matches(object *filterObject, selector labels.Selector) bool {
+	if len(filter.Kinds) > 0 && object.Kind != nil && !containsFold(filter.Kinds, *object.Kind) {
+		return false
+	}
+
+	if len(filter.Names) > 0 && object.Name != nil && !contains(filter.Names, *object.Name) {
+		return false
+	}
+
+	if len(filter.Namespaces) > 0 && object.Namespace != nil && !contains(filter.Namespaces, *object.Namespace) {
+		return false
+	}
+
+	return object.Labels == nil || selector.Matches(object.Labels)
+}
+
+// withObjects returns the manifest with only the kept objects extracted from it,
+// or the manifest itself when all of its objects were kept.  The content of the
+// manifest is retained so that the positions of the kept objects are unchanged.
+func (manifest *Manifest) withObjects(objects, kept []*Object) *Manifest {
+	if len(kept) == len(objects) {
+		return manifest
+	}
+
+	trimmed := *manifest
+	trimmed.excluded = map[int]bool{}
+
+	for document := range manifest.excluded {
+		trimmed.excluded[document] = true
+	}
+
+	for _, object := range objects {
+		trimmed.excluded[object.Document] = true
+	}
+
+	for _, object := range kept {
+		delete(trimmed.excluded, object.Document)
+	}
+
+	return &trimmed
+}
+
+// contains determines if a value is in a list of values.
+func contains(values []string, value string) bool {
+	for i := range values {
+		if values[i] == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// containsFold determines if a value is in a list of values, ignoring case.
+func containsFold(values []string, value string) bool {
+	for i := range values {
+		if strings.EqualFold(values[i], value) {
+			return true
+		}
+	}
+
+	return false
+}
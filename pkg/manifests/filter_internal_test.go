@@ -0,0 +1,141 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package manifests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifests_Filter(t *testing.T) {
+	t.Parallel()
+
+	service := "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n  namespace: shop\n  labels:\n    app: web"
+	deployment := "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n  namespace: shop\n  labels:\n    app: web\n    tier: frontend"
+	role := "apiVersion: rbac.authorization.k8s.io/v1\nkind: ClusterRole\nmetadata:\n  name: reader"
+
+	files := Manifests{
+		{Filename: "web.yaml", Content: []byte(service + "\n---\n" + deployment + "\n")},
+		{Filename: "rbac.yaml", Content: []byte(role + "\n")},
+	}
+
+	tests := []struct {
+		name    string
+		filter  *Filter
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:   "ensure an empty filter selects all objects",
+			filter: &Filter{},
+			want:   []string{service, deployment, role},
+		},
+		{
+			name:   "ensure kinds are matched case insensitively",
+			filter: &Filter{Kinds: []string{"deployment", "ClusterRole"}},
+			want:   []string{deployment, role},
+		},
+		{
+			name:   "ensure names and namespaces are matched together",
+			filter: &Filter{Names: []string{"web", "reader"}, Namespaces: []string{"shop"}},
+			want:   []string{service, deployment},
+		},
+		{
+			name:   "ensure set based label selectors are matched",
+			filter: &Filter{Selector: "app=web,tier in (frontend,backend)"},
+			want:   []string{deployment},
+		},
+		{
+			name:   "ensure label selectors may match unlabeled objects",
+			filter: &Filter{Selector: "!tier"},
+			want:   []string{service, role},
+		},
+		{
+			name:    "ensure invalid label selectors return an error",
+			filter:  &Filter{Selector: "app in web"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := files.Filter(tt.filter, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Filter() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			var objects []string
+			for _, manifest := range *got {
+				objects = append(objects, manifest.ExtractManifests()...)
+			}
+
+			assert.Equal(t, tt.want, objects)
+		})
+	}
+}
+
+func TestManifests_Filter_templating(t *testing.T) {
+	t.Parallel()
+
+	templated := "apiVersion: v1\nkind: Service\nmetadata:\n  name: '{{ .Name }}'\n  labels:\n    app: web"
+	variable := "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: !!var name\n  labels: !!var=labels {}"
+	conditional := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: web\ndata: !!if:enabled\n  key: value"
+	undecodable := "apiVersion: v1\nkind: Secret\n{{- if .TLS }}\nmetadata:\n  name: tls\n{{- end }}"
+
+	files := Manifests{
+		{Filename: "web.yaml", Content: []byte(templated + "\n---\n" + variable + "\n---\n" + conditional + "\n---\n" + undecodable + "\n")},
+	}
+
+	tests := []struct {
+		name   string
+		filter *Filter
+		values map[string]interface{}
+		want   []string
+	}{
+		{
+			name:   "ensure names are matched once templating is resolved by the values",
+			filter: &Filter{Names: []string{"web"}},
+			values: map[string]interface{}{"Name": "web"},
+			want:   []string{templated, variable, conditional},
+		},
+		{
+			name:   "ensure unresolved templating does not exclude objects by name",
+			filter: &Filter{Names: []string{"api"}},
+			want:   []string{templated, variable, undecodable},
+		},
+		{
+			name:   "ensure tagged documents are matched by their known fields",
+			filter: &Filter{Kinds: []string{"ConfigMap"}},
+			want:   []string{conditional, undecodable},
+		},
+		{
+			name:   "ensure unknown labels do not exclude objects",
+			filter: &Filter{Selector: "app=api"},
+			want:   []string{variable, undecodable},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := files.Filter(tt.filter, tt.values)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var objects []string
+			for _, manifest := range *got {
+				objects = append(objects, manifest.ExtractManifests()...)
+			}
+
+			assert.Equal(t, tt.want, objects)
+		})
+	}
+}
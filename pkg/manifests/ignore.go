@@ -0,0 +1,275 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package manifests
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/nukleros/gener8s/pkg/utils"
+)
+
+// IgnoreFilename is the name of the file containing patterns of manifest files to
+// ignore, with gitignore semantics.
+const IgnoreFilename = ".gener8signore"
+
+const (
+	reasonExcluded  = "excluded"
+	reasonNotObject = "missing apiVersion or kind"
+)
+
+// Ignore represents patterns of manifest files to ignore, with gitignore
+// semantics.  Patterns without a slash match at any depth, patterns ending with a
+// slash only match directories, patterns starting with an exclamation mark
+// re-include files and the last matching pattern wins.
+type Ignore struct {
+	patterns []ignorePattern
+}
+
+// ignorePattern represents a single pattern of an Ignore.
+type ignorePattern struct {
+	glob    string
+	negate  bool
+	dirOnly bool
+}
+
+// NewIgnore returns an Ignore for the given patterns.  Empty patterns and those
+// starting with a hash are ignored, as they would be in a gitignore file.
+func NewIgnore(patterns ...string) *Ignore {
+	ignore := &Ignore{}
+	ignore.Add(patterns...)
+
+	return ignore
+}
+
+// ReadIgnoreFile returns an Ignore for the patterns in a file, with an Ignore
+// which matches nothing returned when the file does not exist.
+func ReadIgnoreFile(path string) (*Ignore, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return NewIgnore(), nil
+		}
+
+		return nil, fmt.Errorf("%w; unable to read ignore file %s", err, path)
+	}
+
+	return NewIgnore(strings.Split(string(content), "\n")...), nil
+}
+
+// Add adds patterns to the Ignore, which take precedence over existing patterns.
+func (ignore *Ignore) Add(patterns ...string) {
+	for _, line := range patterns {
+		line = strings.TrimRight(strings.TrimSuffix(line, "\r"), " ")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var pattern ignorePattern
+
+		switch {
+		case strings.HasPrefix(line, "!"):
+			pattern.negate = true
+			line = line[1:]
+		case strings.HasPrefix(line, `\#`), strings.HasPrefix(line, `\!`):
+			line = line[1:]
+		}
+
+		if strings.HasSuffix(line, "/") {
+			pattern.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+
+		// patterns without a slash, other than a trailing one, match at any depth
+		if strings.Contains(line, "/") {
+			line = strings.TrimPrefix(line, "/")
+		} else {
+			line = "**/" + line
+		}
+
+		if line == "" {
+			continue
+		}
+
+		pattern.glob = line
+		ignore.patterns = append(ignore.patterns, pattern)
+	}
+}
+
+// Match reports whether a slash separated path, relative to the directory of the
+// patterns, is ignored.  As with git, a file is ignored when any of its parent
+// directories is ignored.
+func (ignore *Ignore) Match(name string, isDir bool) bool {
+	segments := strings.Split(strings.Trim(name, "/"), "/")
+
+	for i := 1; i < len(segments); i++ {
+		if ignore.match(strings.Join(segments[:i], "/"), true) {
+			return true
+		}
+	}
+
+	return ignore.match(strings.Join(segments, "/"), isDir)
+}
+
+// match reports whether the last pattern matching a path ignores it.
+func (ignore *Ignore) match(name string, isDir bool) bool {
+	for i := len(ignore.patterns) - 1; i >= 0; i-- {
+		pattern := ignore.patterns[i]
+
+		if pattern.dirOnly && !isDir {
+			continue
+		}
+
+		if utils.Match(pattern.glob, name) {
+			return !pattern.negate
+		}
+	}
+
+	return false
+}
+
+// Skipped represents a manifest file, or a document of a manifest file, which was
+// skipped.
+type Skipped struct {
+	Filename string
+	Reason   string
+
+	// Document is the position of the skipped document within the manifest file,
+	// starting from 1, or 0 when the whole manifest file was skipped.
+	Document int
+}
+
+func (skipped Skipped) String() string {
+	if skipped.Document == 0 {
+		return fmt.Sprintf("%s: %s", skipped.Filename, skipped.Reason)
+	}
+
+	return fmt.Sprintf("%s (document %d): %s", skipped.Filename, skipped.Document, skipped.Reason)
+}
+
+// Summary returns a summary of the skipped manifest files and documents.
+func Summary(skipped []Skipped) string {
+	var files, documents int
+
+	for i := range skipped {
+		if skipped[i].Document == 0 {
+			files++
+		} else {
+			documents++
+		}
+	}
+
+	summary := fmt.Sprintf("skipped %d manifest file(s) and %d document(s):\n", files, documents)
+	for i := range skipped {
+		summary = fmt.Sprintf("%s  %s\n", summary, skipped[i])
+	}
+
+	return summary
+}
+
+// Exclude returns the manifests which are not matched by the Ignore, along with
+// the manifests which were skipped.  The filename of each manifest is matched
+// relative to the base directory, with manifests outside of the base directory
+// matched by their filename as given.
+func (manifests *Manifests) Exclude(ignore *Ignore, baseDir string) (*Manifests, []Skipped) {
+	var kept Manifests
+
+	var skipped []Skipped
+
+	for _, manifest := range *manifests {
+		if ignore.Match(manifest.matchPath(baseDir), manifest.isDir()) {
+			skipped = append(skipped, Skipped{Filename: manifest.Filename, Reason: reasonExcluded})
+
+			continue
+		}
+
+		kept = append(kept, manifest)
+	}
+
+	return &kept, skipped
+}
+
+// SkipNonObjects returns the manifests with any documents which have no
+// apiVersion or kind removed, along with the documents which were skipped.
+// Manifests with no remaining documents are removed.  Documents which cannot be
+// decoded, such as those containing templating, are retained.
+func (manifests *Manifests) SkipNonObjects() (*Manifests, []Skipped) {
+	var kept Manifests
+
+	var skipped []Skipped
+
+	for _, manifest := range *manifests {
+		var selected []*Object
+
+		objects := manifest.ExtractObjects()
+
+		for _, object := range objects {
+			var fields struct {
+				APIVersion string `yaml:"apiVersion"`
+				Kind       string `yaml:"kind"`
+			}
+
+			if err := yaml.Unmarshal([]byte(object.Content), &fields); err == nil && (fields.APIVersion == "" || fields.Kind == "") {
+				skipped = append(skipped, Skipped{Filename: manifest.Filename, Reason: reasonNotObject, Document: object.Document})
+
+				continue
+			}
+
+			selected = append(selected, object)
+		}
+
+		if len(selected) > 0 {
+			kept = append(kept, manifest.withObjects(objects, selected))
+		}
+	}
+
+	return &kept, skipped
+}
+
+// matchPath returns the slash separated path of the manifest used for matching
+// against an Ignore.
+func (manifest *Manifest) matchPath(baseDir string) string {
+	if manifest.FS != nil {
+		return manifest.Filename
+	}
+
+	absolute, err := filepath.Abs(manifest.Filename)
+	if err != nil {
+		return filepath.ToSlash(filepath.Clean(manifest.Filename))
+	}
+
+	base, err := filepath.Abs(baseDir)
+	if err != nil {
+		return filepath.ToSlash(filepath.Clean(manifest.Filename))
+	}
+
+	relative, err := filepath.Rel(base, absolute)
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return filepath.ToSlash(filepath.Clean(manifest.Filename))
+	}
+
+	return filepath.ToSlash(relative)
+}
+
+// isDir determines if the manifest filename is a directory, as may be matched by
+// a double-star glob.
+func (manifest *Manifest) isDir() bool {
+	var info fs.FileInfo
+
+	var err error
+
+	if manifest.FS != nil {
+		info, err = fs.Stat(manifest.FS, manifest.Filename)
+	} else {
+		info, err = os.Stat(manifest.Filename)
+	}
+
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,94 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package manifests
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIgnore_Match(t *testing.T) {
+	t.Parallel()
+
+	ignore := NewIgnore(
+		"# test fixtures",
+		"testdata/",
+		"kustomization.yaml",
+		"/charts/*/values.yaml",
+		"config/**/*.orig",
+		"*.tpl.yaml",
+		"!keep.tpl.yaml",
+	)
+
+	tests := []struct {
+		name  string
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{name: "ensure files in ignored directories are matched", path: "config/testdata/service.yaml", want: true},
+		{name: "ensure directory patterns do not match files", path: "config/testdata", want: false},
+		{name: "ensure patterns without a slash match at any depth", path: "config/overlay/kustomization.yaml", want: true},
+		{name: "ensure anchored patterns match from the root", path: "charts/web/values.yaml", want: true},
+		{name: "ensure anchored patterns do not match below the root", path: "config/charts/web/values.yaml", want: false},
+		{name: "ensure double star patterns match any depth", path: "config/a/b/role.yaml.orig", want: true},
+		{name: "ensure negated patterns re-include files", path: "config/keep.tpl.yaml", want: false},
+		{name: "ensure files not matching are not ignored", path: "config/service.yaml", want: false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, ignore.Match(tt.path, tt.isDir))
+		})
+	}
+}
+
+func TestManifests_Exclude(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"config/kustomization.yaml": {Data: []byte("resources: []\n")},
+		"config/service.yaml":       {Data: []byte("kind: Service\n")},
+	}
+
+	files, err := ExpandManifestsFS(fsys, []string{"config/*.yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kept, skipped := files.Exclude(NewIgnore("kustomization.yaml"), ".")
+
+	if assert.Len(t, *kept, 1) {
+		assert.Equal(t, "config/service.yaml", (*kept)[0].Filename)
+	}
+
+	assert.Equal(t, []Skipped{{Filename: "config/kustomization.yaml", Reason: reasonExcluded}}, skipped)
+}
+
+func TestManifests_SkipNonObjects(t *testing.T) {
+	t.Parallel()
+
+	files := Manifests{
+		{Filename: "values.yaml", Content: []byte("replicas: 1\n")},
+		{Filename: "web.yaml", Content: []byte("apiVersion: v1\nkind: Service\n---\nfoo: bar\n---\napiVersion: {{ .Version }}\nkind: Deployment\n")},
+	}
+
+	kept, skipped := files.SkipNonObjects()
+
+	if assert.Len(t, *kept, 1) {
+		assert.Equal(t, []string{"apiVersion: v1\nkind: Service", "apiVersion: {{ .Version }}\nkind: Deployment"}, (*kept)[0].ExtractManifests())
+	}
+
+	assert.Equal(t, []Skipped{
+		{Filename: "values.yaml", Reason: reasonNotObject, Document: 1},
+		{Filename: "web.yaml", Reason: reasonNotObject, Document: 2},
+	}, skipped)
+	assert.Equal(t, "skipped 0 manifest file(s) and 2 document(s):\n"+
+		"  values.yaml (document 1): missing apiVersion or kind\n"+
+		"  web.yaml (document 2): missing apiVersion or kind\n", Summary(skipped))
+}
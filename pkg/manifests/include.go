@@ -0,0 +1,221 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package manifests
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"regexp"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ErrIncludeFile = errors.New("unable to include file")
+
+// includeTags are the tags of scalars which are replaced with the content of a
+// file, and includePattern matches such a tag, along with its optional :base64
+// suffix and the path of the file to include, at the start of the scalar.
+var (
+	includeTags    = []string{"!!include", "!!file"}
+	includePattern = regexp.MustCompile(`^!!(?:include|file)(:base64)?[ \t]+("(?:[^"\\]|\\.)*"|'[^']*'|[^\s#]+)`)
+)
+
+// include represents a scalar tagged to be replaced with the content of a file.
+type include struct {
+	Path   string
+	Encode bool
+
+	// Line is the line of the scalar, and Start and End are the offsets of its
+	// tag and value within the content of the manifest.
+	Line  int
+	Start int
+	End   int
+}
+
+// ResolveIncludes replaces each scalar tagged with !!include (or its alias !!file)
+// with the content of the file at the given path, relative to the manifest
+// file and read from the file system of the manifest.  When the tag is
+// !!include:base64 the content is base64 encoded, as is needed for the data of a
+// Secret.  Only tagged scalars are replaced, so that tags within comments,
+// strings and block scalars are left as is.
+func (manifest *Manifest) ResolveIncludes() error {
+	includes, err := manifest.includes()
+	if err != nil {
+		return fmt.Errorf("%w; %s for manifest file %s", err, ErrIncludeFile, manifest.Filename)
+	}
+
+	baseDir := filepath.Dir(manifest.Filename)
+	if manifest.FS != nil {
+		baseDir = path.Dir(manifest.Filename)
+	}
+
+	// replace the scalars from the end, so that the offsets of the others remain
+	for i := len(includes) - 1; i >= 0; i-- {
+		content, err := manifest.includeContent(baseDir, includes[i].Path, includes[i].Encode)
+		if err != nil {
+			return fmt.Errorf("%w; %s %s at line %d for manifest file %s",
+				err, ErrIncludeFile, includes[i].Path, includes[i].Line, manifest.Filename)
+		}
+
+		resolved := make([]byte, 0, len(manifest.Content)+len(content))
+		resolved = append(resolved, manifest.Content[:includes[i].Start]...)
+		resolved = append(resolved, content...)
+		manifest.Content = append(resolved, manifest.Content[includes[i].End:]...)
+	}
+
+	return nil
+}
+
+// includes returns the scalars of the content of a manifest which are tagged to
+// be replaced with the content of a file, in order.  Content without the tags
+// is not parsed, as it may not be yaml.
+func (manifest *Manifest) includes() ([]include, error) {
+	tagged := false
+
+	for _, tag := range includeTags {
+		tagged = tagged || bytes.Contains(manifest.Content, []byte(tag))
+	}
+
+	if !tagged {
+		return nil, nil
+	}
+
+	var includes []include
+
+	decoder := yaml.NewDecoder(bytes.NewReader(manifest.Content))
+
+	for {
+		var node yaml.Node
+		if err := decoder.Decode(&node); err != nil {
+			if errors.Is(err, io.EOF) {
+				return includes, nil
+			}
+
+			return nil, fmt.Errorf("%w; unable to parse manifest to include files", err)
+		}
+
+		documentIncludes, err := manifest.nodeIncludes(&node)
+		if err != nil {
+			return nil, err
+		}
+
+		includes = append(includes, documentIncludes...)
+	}
+}
+
+// nodeIncludes returns the scalars of a node which are tagged to be replaced with
+// the content of a file, in order.
+func (manifest *Manifest) nodeIncludes(node *yaml.Node) ([]include, error) {
+	if node.Kind == yaml.ScalarNode && isIncludeTag(node.Tag) {
+		start := offset(manifest.Content, node.Line, node.Column)
+
+		// the extent of the scalar is not recorded by the parser, so it is
+		// matched from its tag, which must be followed by its value on the line
+		match := includePattern.FindSubmatchIndex(manifest.Content[start:])
+		if match == nil || node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+			return nil, fmt.Errorf("%w; the path of the file must follow tag %s on line %d",
+				ErrIncludeFile, node.Tag, node.Line)
+		}
+
+		return []include{{
+			Path:   node.Value,
+			Encode: match[2] >= 0,
+			Line:   node.Line,
+			Start:  start,
+			End:    start + match[1],
+		}}, nil
+	}
+
+	var includes []include
+
+	for _, child := range node.Content {
+		childIncludes, err := manifest.nodeIncludes(child)
+		if err != nil {
+			return nil, err
+		}
+
+		includes = append(includes, childIncludes...)
+	}
+
+	return includes, nil
+}
+
+// isIncludeTag determines if a yaml tag is a tag of a scalar which is replaced
+// with the content of a file.
+func isIncludeTag(tag string) bool {
+	for _, includeTag := range includeTags {
+		if tag == includeTag || tag == includeTag+":base64" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// offset returns the offset within content of a position, with a line and a
+// column in characters, starting from 1.
+func offset(content []byte, line, column int) int {
+	start := 0
+
+	for i := 1; i < line; i++ {
+		next := bytes.IndexByte(content[start:], '\n')
+		if next < 0 {
+			return len(content)
+		}
+
+		start += next + 1
+	}
+
+	for i := 1; i < column && start < len(content); i++ {
+		_, size := utf8.DecodeRune(content[start:])
+		start += size
+	}
+
+	return start
+}
+
+// includeContent returns the content of an included file as a yaml double quoted
+// scalar.
+func (manifest *Manifest) includeContent(baseDir, name string, encode bool) ([]byte, error) {
+	switch {
+	case manifest.FS != nil:
+		name = path.Join(baseDir, name)
+	case !filepath.IsAbs(name):
+		name = filepath.Join(baseDir, name)
+	}
+
+	content, err := manifest.readFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w; unable to read included file %s", err, name)
+	}
+
+	value := string(content)
+	if encode {
+		value = base64.StdEncoding.EncodeToString(content)
+	}
+
+	// json strings are valid yaml double quoted scalars
+	var buf bytes.Buffer
+
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+
+	if err := encoder.Encode(value); err != nil {
+		return nil, fmt.Errorf("%w; unable to encode included file %s", err, name)
+	}
+
+	// escape braces so that included content is not treated as templating when
+	// a manifest is templated, and dashes so that it is not treated as a
+	// document separator when manifests are extracted
+	scalar := bytes.ReplaceAll(bytes.TrimSpace(buf.Bytes()), []byte("{"), []byte(`\u007b`))
+
+	return bytes.ReplaceAll(scalar, []byte("---"), []byte(`\u002d\u002d\u002d`)), nil
+}
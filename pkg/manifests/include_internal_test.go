@@ -0,0 +1,116 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package manifests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestManifest_ResolveIncludes(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "nginx.conf"), []byte("http {\n  {{ .Port }}\n}\n---\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "ensure included file content is set verbatim",
+			content: "nginx.conf: !!include nginx.conf  # the config",
+			want:    map[string]string{"nginx.conf": "http {\n  {{ .Port }}\n}\n---\n"},
+		},
+		{
+			name:    "ensure included file content is base64 encoded",
+			content: "nginx.conf: !!file:base64 'nginx.conf'",
+			want:    map[string]string{"nginx.conf": "aHR0cCB7CiAge3sgLlBvcnQgfX0KfQotLS0K"},
+		},
+		{
+			name: "ensure tags within comments, strings and block scalars are not included",
+			content: "# nginx.conf: !!include nginx.conf\n" +
+				"nginx.conf: !!include nginx.conf\n" +
+				"quoted: 'see !!include nginx.conf'\n" +
+				"script: |\n  echo !!file nginx.conf\n",
+			want: map[string]string{
+				"nginx.conf": "http {\n  {{ .Port }}\n}\n---\n",
+				"quoted":     "see !!include nginx.conf",
+				"script":     "echo !!file nginx.conf\n",
+			},
+		},
+		{
+			name:    "ensure missing included file returns an error",
+			content: "nginx.conf: !!include missing.conf",
+			wantErr: true,
+		},
+		{
+			name:    "ensure included file without a path on the line of its tag returns an error",
+			content: "nginx.conf: !!include |\n  nginx.conf\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			manifest := &Manifest{
+				Filename: filepath.Join(dir, "manifest.yaml"),
+				Content:  []byte(tt.content),
+			}
+			err := manifest.ResolveIncludes()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ResolveIncludes() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			assert.NotContains(t, string(manifest.Content), "{{")
+			assert.NotContains(t, string(manifest.Content), "---")
+			got := map[string]string{}
+			if err := yaml.Unmarshal(manifest.Content, &got); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestManifest_ResolveIncludes_documents(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "app.conf"), []byte("port 80"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest := &Manifest{
+		Filename: filepath.Join(dir, "manifest.yaml"),
+		Content:  []byte("kind: ConfigMap\n---\nkind: Secret\ndata:\n  app.conf: !!file:base64 \"app.conf\"\n  other: !!include missing.conf\n"),
+	}
+
+	err := manifest.ResolveIncludes()
+	assert.ErrorContains(t, err, "missing.conf at line 6")
+
+	manifest.Content = []byte("kind: ConfigMap\n---\nkind: Secret\ndata:\n  app.conf: !!file:base64 \"app.conf\"\n")
+
+	if err := manifest.ResolveIncludes(); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "kind: ConfigMap\n---\nkind: Secret\ndata:\n  app.conf: \"cG9ydCA4MA==\"\n", string(manifest.Content))
+}
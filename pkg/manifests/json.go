@@ -0,0 +1,164 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package manifests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInvalidJSON = errors.New("invalid json manifest")
+
+// isJSON determines if the content of a manifest is json rather than yaml, which
+// is the case when the first value is an object or an array.
+func isJSON(content []byte) bool {
+	trimmed := bytes.TrimSpace(content)
+
+	return len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[')
+}
+
+// extractJSON extracts the objects from json content as yaml, along with the
+// lines they start on.  The content may be a single object, an array of objects
+// or a stream of either, such as newline delimited json.
+func extractJSON(content []byte) ([]*Object, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+
+	var objects []*Object
+
+	for {
+		node, err := decodeJSON(decoder, content)
+		if errors.Is(err, io.EOF) {
+			return objects, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		nodes := []*yaml.Node{node}
+		if node.Kind == yaml.SequenceNode {
+			nodes = node.Content
+		}
+
+		for _, object := range nodes {
+			var buf bytes.Buffer
+
+			encoder := yaml.NewEncoder(&buf)
+			encoder.SetIndent(2)
+
+			if err := encoder.Encode(object); err != nil {
+				return nil, fmt.Errorf("%w; %s", err, ErrInvalidJSON)
+			}
+
+			objects = append(objects, &Object{
+				Content:   strings.TrimSpace(buf.String()),
+				Line:      object.Line,
+				converted: true,
+			})
+		}
+	}
+}
+
+// decodeJSON decodes the next json value as a yaml node, retaining the order of
+// the keys of objects, as well as the literal form of numbers.  The line of the
+// node is set to the line of the value within the content.
+func decodeJSON(decoder *json.Decoder, content []byte) (*yaml.Node, error) {
+	line := jsonLine(content, decoder.InputOffset())
+
+	node, err := decodeJSONValue(decoder, content)
+	if err != nil {
+		return nil, err
+	}
+
+	node.Line = line
+
+	return node, nil
+}
+
+// decodeJSONValue decodes the next json value as a yaml node.
+func decodeJSONValue(decoder *json.Decoder, content []byte) (*yaml.Node, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+
+		return nil, fmt.Errorf("%w; %s", err, ErrInvalidJSON)
+	}
+
+	switch value := token.(type) {
+	case json.Delim:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		if value == '[' {
+			node = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		}
+
+		for decoder.More() {
+			if node.Kind == yaml.MappingNode {
+				key, err := decodeJSON(decoder, content)
+				if err != nil {
+					return nil, unexpectedEOF(err)
+				}
+
+				node.Content = append(node.Content, key)
+			}
+
+			item, err := decodeJSON(decoder, content)
+			if err != nil {
+				return nil, unexpectedEOF(err)
+			}
+
+			node.Content = append(node.Content, item)
+		}
+
+		// consume the closing delimiter
+		if _, err := decoder.Token(); err != nil {
+			return nil, fmt.Errorf("%w; %s", err, ErrInvalidJSON)
+		}
+
+		return node, nil
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}, nil
+	case json.Number:
+		tag := "!!float"
+		if _, err := value.Int64(); err == nil {
+			tag = "!!int"
+		}
+
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value.String()}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(value)}, nil
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+}
+
+// unexpectedEOF returns an error for the end of the content in the middle of a
+// json value.
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w; %s", io.ErrUnexpectedEOF, ErrInvalidJSON)
+	}
+
+	return err
+}
+
+// jsonLine returns the line of the next json value after an offset within the
+// content, skipping any whitespace and delimiters between values.
+func jsonLine(content []byte, offset int64) int {
+	start := int(offset)
+	for start < len(content) && strings.ContainsRune(" \t\r\n,:", rune(content[start])) {
+		start++
+	}
+
+	return bytes.Count(content[:start], []byte("\n")) + 1
+}
@@ -0,0 +1,60 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package manifests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifest_ExtractManifests_JSON(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "ensure a single object is converted to yaml in order",
+			content: `{"kind": "Service", "apiVersion": "v1", "spec": {"ports": [{"port": 80}]}}`,
+			want:    []string{"kind: Service\napiVersion: v1\nspec:\n  ports:\n    - port: 80"},
+		},
+		{
+			name:    "ensure an array of objects is split",
+			content: `[{"kind": "Service"}, {"kind": "Deployment"}]`,
+			want:    []string{"kind: Service", "kind: Deployment"},
+		},
+		{
+			name:    "ensure newline delimited objects are split",
+			content: "{\"kind\": \"Service\"}\n{\"kind\": \"Deployment\"}\n",
+			want:    []string{"kind: Service", "kind: Deployment"},
+		},
+		{
+			name:    "ensure scalar types are retained",
+			content: `{"port": "80", "replicas": 2, "ratio": 0.5, "enabled": true, "path": "a\/---\/b", "empty": null}`,
+			want:    []string{"port: \"80\"\nreplicas: 2\nratio: 0.5\nenabled: true\npath: a/---/b\nempty: null"},
+		},
+		{
+			name:    "ensure yaml is split on document separators",
+			content: "kind: Service\n---\nkind: Deployment\n",
+			want:    []string{"kind: Service", "kind: Deployment"},
+		},
+		{
+			name:    "ensure invalid json is left to the yaml decoding",
+			content: `{"kind": "Service"`,
+			want:    []string{`{"kind": "Service"`},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			manifest := &Manifest{Content: []byte(tt.content)}
+			assert.Equal(t, tt.want, manifest.ExtractManifests())
+		})
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package manifests
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"sigs.k8s.io/kustomize/api/konfig"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/yaml"
+)
+
+var ErrProcessKustomization = errors.New("error processing kustomization")
+
+// FromKustomization builds the kustomization in a local directory and returns a
+// manifest for each of the rendered objects.  The filename of each manifest is
+// the file that the object originated from.
+func FromKustomization(kustomizationDir string) (*Manifests, error) {
+	root, err := filepath.Abs(kustomizationDir)
+	if err != nil {
+		return &Manifests{}, fmt.Errorf("%w; %s for directory %s", err, ErrProcessKustomization, kustomizationDir)
+	}
+
+	fs := &originFileSystem{FileSystem: filesys.MakeFsOnDisk(), root: root}
+
+	resources, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(fs, root)
+	if err != nil {
+		return &Manifests{}, fmt.Errorf("%w; %s for directory %s", err, ErrProcessKustomization, kustomizationDir)
+	}
+
+	manifests := make(Manifests, 0, resources.Size())
+
+	for _, resource := range resources.Resources() {
+		origin, err := resource.GetOrigin()
+		if err != nil {
+			return &Manifests{}, fmt.Errorf("%w; %s for directory %s", err, ErrProcessKustomization, kustomizationDir)
+		}
+
+		manifest := &Manifest{
+			Filename:         root,
+			RelativeFilename: kustomizationDir,
+		}
+
+		if origin != nil && origin.Repo == "" {
+			// generated objects originate from the file the generator is configured in
+			path := origin.Path
+			if origin.ConfiguredIn != "" {
+				path = origin.ConfiguredIn
+			}
+
+			manifest.Filename = filepath.Join(root, path)
+			manifest.RelativeFilename = filepath.Join(kustomizationDir, path)
+		}
+
+		// only retain the origin annotation if it was requested by the kustomization
+		if !fs.originRequested {
+			if err := resource.SetOrigin(nil); err != nil {
+				return &Manifests{}, fmt.Errorf("%w; %s for directory %s", err, ErrProcessKustomization, kustomizationDir)
+			}
+		}
+
+		if manifest.Content, err = resource.AsYAML(); err != nil {
+			return &Manifests{}, fmt.Errorf("%w; %s for directory %s", err, ErrProcessKustomization, kustomizationDir)
+		}
+
+		manifests = append(manifests, manifest)
+	}
+
+	return &manifests, nil
+}
+
+// originFileSystem is a file system which enables the origin annotations for the
+// root kustomization, so that the source file of each object is known.
+type originFileSystem struct {
+	filesys.FileSystem
+
+	root            string
+	originRequested bool
+}
+
+// ReadFile reads a file, adding the origin annotations build metadata option if
+// the file is the root kustomization.
+func (fs *originFileSystem) ReadFile(path string) ([]byte, error) {
+	content, err := fs.FileSystem.ReadFile(path)
+	if err != nil || filepath.Dir(path) != fs.root || !isKustomizationFile(filepath.Base(path)) {
+		return content, err //nolint:wrapcheck
+	}
+
+	kustomization := map[string]interface{}{}
+	if err := yaml.Unmarshal(content, &kustomization); err != nil {
+		return content, nil
+	}
+
+	buildMetadata, _ := kustomization["buildMetadata"].([]interface{})
+
+	for _, option := range buildMetadata {
+		if option == types.OriginAnnotations {
+			fs.originRequested = true
+
+			return content, nil
+		}
+	}
+
+	kustomization["buildMetadata"] = append(buildMetadata, types.OriginAnnotations)
+
+	return yaml.Marshal(kustomization) //nolint:wrapcheck
+}
+
+// isKustomizationFile determines if a file name is a recognized kustomization
+// file name.
+func isKustomizationFile(name string) bool {
+	for _, recognized := range konfig.RecognizedKustomizationFileNames() {
+		if name == recognized {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,48 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package manifests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromKustomization(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"base/kustomization.yaml":    "resources:\n- service.yaml\n",
+		"base/service.yaml":          "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n",
+		"overlay/kustomization.yaml": "resources:\n- ../base\nnamespace: web\n",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := FromKustomization(filepath.Join(dir, "overlay"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, *got, 1)
+	assert.Equal(t, filepath.Join(dir, "base", "service.yaml"), (*got)[0].Filename)
+	assert.Contains(t, string((*got)[0].Content), "namespace: web")
+	assert.NotContains(t, string((*got)[0].Content), "config.kubernetes.io/origin")
+
+	_, err = FromKustomization(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
@@ -6,9 +6,10 @@ package manifests
 import (
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/nukleros/gener8s/pkg/utils"
 )
@@ -21,11 +22,27 @@ const (
 	WithParentPath ManifestOptions = iota
 )
 
+// StdinFilename is the filename of a manifest read from standard input.
+const StdinFilename = "<stdin>"
+
 // Manifest represents a single input manifest for a given config.
 type Manifest struct {
 	Content          []byte
 	Filename         string
 	RelativeFilename string
+
+	// FS is the file system the manifest, and any files it includes, are loaded
+	// from.  The manifest is loaded from the local file system when nil.
+	FS fs.FS
+
+	// excluded are the positions of the documents which are excluded when
+	// extracting the objects of the manifest, such as by a filter.
+	excluded map[int]bool
+
+	// replaced is the content which replaces the content of documents, by their
+	// position, when extracting the objects of the manifest, such as objects
+	// merged from several definitions.
+	replaced map[int]string
 }
 
 // Manifests represents a collection of manifests.
@@ -61,32 +78,79 @@ func ExpandManifests(parentPath string, manifestPaths []string) (*Manifests, err
 	return &manifests, nil
 }
 
-// ExtractManifests extracts the manifests as YAML strings from a manifest with
-// existing manifest content.
-func (manifest *Manifest) ExtractManifests() []string {
-	var manifests []string
+// ExpandManifestsFS expands manifests from their globbed patterns over the
+// provided file system, such as an embed.FS, and returns the resultant manifests,
+// which are loaded from that file system.
+func ExpandManifestsFS(fsys fs.FS, manifestPaths []string) (*Manifests, error) {
+	var manifests Manifests
 
-	manifestYaml := strings.Split(string(manifest.Content), "---")
+	for i := range manifestPaths {
+		files, err := utils.GlobFS(fsys, manifestPaths[i])
+		if err != nil {
+			return &Manifests{}, fmt.Errorf("failed to process glob pattern matching, %w", err)
+		}
 
-	for _, object := range manifestYaml {
-		object = strings.TrimSpace(object)
-		if object == "" {
-			continue
+		for f := range files {
+			manifest := &Manifest{Filename: files[f], RelativeFilename: files[f], FS: fsys}
+			manifests = append(manifests, manifest)
 		}
-		manifests = append(manifests, object)
+	}
+
+	return &manifests, nil
+}
+
+// FromReader returns a manifest with the content read from the provided reader,
+// such as standard input, with the content of any included files resolved
+// relative to the current directory.
+func FromReader(reader io.Reader, filename string) (*Manifest, error) {
+	manifest := &Manifest{Filename: filename, RelativeFilename: filename}
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return &Manifest{}, fmt.Errorf("%w; %s for manifest file %s", err, ErrProcessManifest, filename)
+	}
+
+	manifest.Content = content
+
+	if err := manifest.ResolveIncludes(); err != nil {
+		return &Manifest{}, err
+	}
+
+	return manifest, nil
+}
+
+// ExtractManifests extracts the manifests as YAML strings from a manifest with
+// existing manifest content.  Manifests with JSON content, whether a single object,
+// an array of objects or newline delimited JSON, are converted to YAML.
+func (manifest *Manifest) ExtractManifests() []string {
+	objects := manifest.ExtractObjects()
+
+	manifests := make([]string, len(objects))
+	for i := range objects {
+		manifests[i] = objects[i].Content
 	}
 
 	return manifests
 }
 
-// LoadContent sets the Content field of the manifest in raw format as []byte.
+// LoadContent sets the Content field of the manifest in raw format as []byte,
+// with the content of any included files resolved.
 func (manifest *Manifest) LoadContent() error {
-	manifestContent, err := os.ReadFile(manifest.Filename)
+	manifestContent, err := manifest.readFile(manifest.Filename)
 	if err != nil {
 		return fmt.Errorf("%w; %s for manifest file %s", err, ErrProcessManifest, manifest.Filename)
 	}
 
 	manifest.Content = manifestContent
 
-	return nil
+	return manifest.ResolveIncludes()
+}
+
+// readFile reads a file from the file system of the manifest.
+func (manifest *Manifest) readFile(name string) ([]byte, error) {
+	if manifest.FS != nil {
+		return fs.ReadFile(manifest.FS, name) //nolint:wrapcheck
+	}
+
+	return os.ReadFile(name) //nolint:wrapcheck
 }
@@ -0,0 +1,97 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package manifests
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandManifestsFS(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"config/deployment.yaml":     {Data: []byte("kind: Deployment\ndata:\n  nginx.conf: !!include nginx.conf\n")},
+		"config/nginx.conf":          {Data: []byte("http {}\n")},
+		"config/rbac/role.yaml":      {Data: []byte("kind: Role\n")},
+		"config/rbac/role.yaml.orig": {Data: []byte("kind: Role\n")},
+	}
+
+	tests := []struct {
+		name     string
+		patterns []string
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name:     "ensure plain paths are expanded",
+			patterns: []string{"config/deployment.yaml"},
+			want:     []string{"config/deployment.yaml"},
+		},
+		{
+			name:     "ensure single star globs are expanded",
+			patterns: []string{"config/*.yaml"},
+			want:     []string{"config/deployment.yaml"},
+		},
+		{
+			name:     "ensure double star globs are expanded",
+			patterns: []string{"**/*.yaml"},
+			want:     []string{"config/deployment.yaml", "config/rbac/role.yaml"},
+		},
+		{
+			name:     "ensure missing files return an error",
+			patterns: []string{"config/missing.yaml"},
+			wantErr:  true,
+		},
+		{
+			name:     "ensure globs without matches return an error",
+			patterns: []string{"**/*.json"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := ExpandManifestsFS(fsys, tt.patterns)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ExpandManifestsFS() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			var filenames []string
+			for _, manifest := range *got {
+				filenames = append(filenames, manifest.Filename)
+			}
+
+			assert.Equal(t, tt.want, filenames)
+		})
+	}
+
+	got, err := ExpandManifestsFS(fsys, []string{"config/deployment.yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if assert.NoError(t, (*got)[0].LoadContent()) {
+		assert.Contains(t, string((*got)[0].Content), `nginx.conf: "http \u007b}\n"`)
+	}
+}
+
+func TestFromReader(t *testing.T) {
+	t.Parallel()
+
+	got, err := FromReader(strings.NewReader("kind: Service\n---\nkind: Deployment\n"), StdinFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, StdinFilename, got.Filename)
+	assert.Equal(t, []string{"kind: Service", "kind: Deployment"}, got.ExtractManifests())
+}
@@ -0,0 +1,213 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package manifests
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// documentSeparator is the separator of the yaml documents of a manifest.
+const documentSeparator = "---"
+
+// yamlErrorLine matches the line reported by the errors of yaml decoders, which
+// is relative to the start of the decoded document.
+var yamlErrorLine = regexp.MustCompile(`yaml: line (\d+): `)
+
+// Object represents a single object extracted from a manifest, along with its
+// position within the manifest file.
+type Object struct {
+	// Content is the yaml content of the object.
+	Content string
+
+	// Filename is the file the object was extracted from.
+	Filename string
+
+	// Document is the position of the object within the manifest file, starting
+	// from 1.
+	Document int
+
+	// Line is the line the object starts on within the manifest file, starting
+	// from 1.
+	Line int
+
+	// converted determines if the content was converted from json, or merged
+	// from several definitions, in which case positions within the content do
+	// not correspond to the manifest file.
+	converted bool
+}
+
+// PositionError represents an error at a position within a manifest file.  The
+// filename is omitted when the position is within content which did not come
+// from a file.
+type PositionError struct {
+	Filename string
+	Line     int
+	Column   int
+	Err      error
+}
+
+func (err *PositionError) Error() string {
+	position := fmt.Sprintf("%d:%d", err.Line, err.Column)
+	if err.Filename != "" {
+		position = fmt.Sprintf("%s:%s", err.Filename, position)
+	}
+
+	return fmt.Sprintf("%s: %s", position, err.Err)
+}
+
+func (err *PositionError) Unwrap() error {
+	return err.Err
+}
+
+// ExtractObjects extracts the objects, as YAML, from a manifest with existing
+// manifest content, along with their positions.  Manifests with JSON content,
+// whether a single object, an array of objects or newline delimited JSON, are
+// converted to YAML.
+func (manifest *Manifest) ExtractObjects() []*Object {
+	var objects []*Object
+
+	// content which is not valid json is left to be reported by the yaml decoding
+	converted := false
+
+	if isJSON(manifest.Content) {
+		if jsonObjects, err := extractJSON(manifest.Content); err == nil {
+			objects, converted = jsonObjects, true
+		}
+	}
+
+	if !converted {
+		objects = extractYAML(string(manifest.Content))
+	}
+
+	kept := make([]*Object, 0, len(objects))
+
+	for i, object := range objects {
+		object.Filename = manifest.Filename
+		object.Document = i + 1
+
+		if manifest.excluded[object.Document] {
+			continue
+		}
+
+		if content, ok := manifest.replaced[object.Document]; ok {
+			object.Content, object.converted = content, true
+		}
+
+		kept = append(kept, object)
+	}
+
+	return kept
+}
+
+// ExtractObjects extracts the objects from all of the manifests, in order, along
+// with their positions.
+func (manifests *Manifests) ExtractObjects() []*Object {
+	var objects []*Object
+
+	for _, manifest := range *manifests {
+		objects = append(objects, manifest.ExtractObjects()...)
+	}
+
+	return objects
+}
+
+// extractYAML extracts the yaml documents from content, along with the lines
+// they start on.
+func extractYAML(content string) []*Object {
+	var objects []*Object
+
+	for offset := 0; offset <= len(content); {
+		end := strings.Index(content[offset:], documentSeparator)
+		if end < 0 {
+			end = len(content) - offset
+		}
+
+		document := content[offset : offset+end]
+		trimmed := strings.TrimSpace(document)
+
+		if trimmed != "" {
+			start := offset + strings.Index(document, trimmed)
+
+			objects = append(objects, &Object{
+				Content: trimmed,
+				Line:    strings.Count(content[:start], "\n") + 1,
+			})
+		}
+
+		offset += end + len(documentSeparator)
+	}
+
+	return objects
+}
+
+// Wrap returns an error with the position of the object within its manifest
+// file.  Errors at a position within the object, either as a PositionError
+// without a filename or as reported by a yaml decoder, are reported at that
+// position within the manifest file, otherwise at the start of the object.
+func (object *Object) Wrap(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var positionErr *PositionError
+	if !errors.As(YAMLError(err), &positionErr) {
+		return &PositionError{Filename: object.Filename, Line: object.Line, Column: 1, Err: err}
+	}
+
+	if positionErr.Filename != "" {
+		return positionErr
+	}
+
+	return &PositionError{
+		Filename: object.Filename,
+		Line:     object.line(positionErr.Line),
+		Column:   object.column(positionErr.Column),
+		Err:      positionErr.Err,
+	}
+}
+
+// YAMLError returns an error from a yaml decoder as a PositionError at the line
+// it reports, relative to the decoded content, or the error itself when no line
+// is reported.
+func YAMLError(err error) error {
+	var positionErr *PositionError
+	if errors.As(err, &positionErr) {
+		return err
+	}
+
+	match := yamlErrorLine.FindStringSubmatch(err.Error())
+	if match == nil {
+		return err
+	}
+
+	line, convErr := strconv.Atoi(match[1])
+	if convErr != nil {
+		return err
+	}
+
+	return &PositionError{Line: line, Column: 1, Err: err}
+}
+
+// line returns the line within the manifest file of a line within the object.
+func (object *Object) line(line int) int {
+	if object.converted || line < 1 {
+		return object.Line
+	}
+
+	return object.Line + line - 1
+}
+
+// column returns the column within the manifest file of a column within the
+// object.
+func (object *Object) column(column int) int {
+	if object.converted || column < 1 {
+		return 1
+	}
+
+	return column
+}
@@ -0,0 +1,114 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package manifests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestManifest_ExtractObjects(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		want    []*Object
+	}{
+		{
+			name:    "ensure the lines of yaml documents are retained",
+			content: "---\nkind: Service\n---\n\n# the deployment\nkind: Deployment\n",
+			want: []*Object{
+				{Content: "kind: Service", Filename: "web.yaml", Document: 1, Line: 2},
+				{Content: "# the deployment\nkind: Deployment", Filename: "web.yaml", Document: 2, Line: 5},
+			},
+		},
+		{
+			name:    "ensure the lines of json objects are retained",
+			content: "[\n  {\"kind\": \"Service\"},\n  {\n    \"kind\": \"Deployment\"\n  }\n]\n",
+			want: []*Object{
+				{Content: "kind: Service", Filename: "web.yaml", Document: 1, Line: 2, converted: true},
+				{Content: "kind: Deployment", Filename: "web.yaml", Document: 2, Line: 3, converted: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			manifest := &Manifest{Filename: "web.yaml", Content: []byte(tt.content)}
+			assert.Equal(t, tt.want, manifest.ExtractObjects())
+		})
+	}
+}
+
+func TestObject_Wrap(t *testing.T) {
+	t.Parallel()
+
+	object := &Object{Filename: "web.yaml", Document: 2, Line: 10}
+
+	var node yaml.Node
+	yamlErr := yaml.Unmarshal([]byte("kind: Service\nspec: [\n"), &node)
+
+	tests := []struct {
+		name   string
+		object *Object
+		err    error
+		want   string
+	}{
+		{
+			name:   "ensure errors without a position are reported at the start of the object",
+			object: object,
+			err:    errors.New("unable to decode object"),
+			want:   "web.yaml:10:1: unable to decode object",
+		},
+		{
+			name:   "ensure positions within the object are offset by its line",
+			object: object,
+			err:    &PositionError{Line: 3, Column: 7, Err: errors.New("invalid variable reference")},
+			want:   "web.yaml:12:7: invalid variable reference",
+		},
+		{
+			name:   "ensure lines reported by yaml decoders are offset by the line of the object",
+			object: object,
+			err:    yamlErr,
+			want:   "web.yaml:11:1: " + yamlErr.Error(),
+		},
+		{
+			name:   "ensure positions within converted objects are reported at the start of the object",
+			object: &Object{Filename: "web.json", Line: 4, converted: true},
+			err:    &PositionError{Line: 3, Column: 7, Err: errors.New("invalid variable reference")},
+			want:   "web.json:4:1: invalid variable reference",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.EqualError(t, tt.object.Wrap(tt.err), tt.want)
+		})
+	}
+}
+
+func TestManifests_Filter_Positions(t *testing.T) {
+	t.Parallel()
+
+	files := Manifests{
+		{Filename: "web.yaml", Content: []byte("kind: Service\n---\nkind: Deployment\n")},
+	}
+
+	got, err := files.Filter(&Filter{Kinds: []string{"Deployment"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []*Object{
+		{Content: "kind: Deployment", Filename: "web.yaml", Document: 2, Line: 3},
+	}, got.ExtractObjects())
+}
@@ -0,0 +1,35 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package manifests
+
+// SplitObjects returns a set of manifests for each object of the manifests, in
+// order.  The content of each manifest is retained so that the positions of the
+// objects are unchanged.
+func (manifests *Manifests) SplitObjects() []*Manifests {
+	var split []*Manifests
+
+	for _, manifest := range *manifests {
+		objects := manifest.ExtractObjects()
+
+		for _, object := range objects {
+			split = append(split, &Manifests{manifest.withObjects(objects, []*Object{object})})
+		}
+	}
+
+	return split
+}
+
+// SplitSources returns a set of manifests for each manifest file with objects,
+// in order.
+func (manifests *Manifests) SplitSources() []*Manifests {
+	var split []*Manifests
+
+	for _, manifest := range *manifests {
+		if len(manifest.ExtractObjects()) > 0 {
+			split = append(split, &Manifests{manifest})
+		}
+	}
+
+	return split
+}
@@ -0,0 +1,48 @@
+// Copyright 2021 VMware, Inc.
+// SPDX-License-Identifier: MIT
+
+package manifests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifests_SplitObjects(t *testing.T) {
+	t.Parallel()
+
+	files := Manifests{
+		{Filename: "web.yaml", Content: []byte("kind: Service\n---\nkind: Deployment\n")},
+		{Filename: "empty.yaml", Content: []byte("\n")},
+		{Filename: "db.yaml", Content: []byte("kind: StatefulSet\n")},
+	}
+
+	var got [][]*Object
+	for _, split := range files.SplitObjects() {
+		got = append(got, split.ExtractObjects())
+	}
+
+	assert.Equal(t, [][]*Object{
+		{{Content: "kind: Service", Filename: "web.yaml", Document: 1, Line: 1}},
+		{{Content: "kind: Deployment", Filename: "web.yaml", Document: 2, Line: 3}},
+		{{Content: "kind: StatefulSet", Filename: "db.yaml", Document: 1, Line: 1}},
+	}, got)
+}
+
+func TestManifests_SplitSources(t *testing.T) {
+	t.Parallel()
+
+	files := Manifests{
+		{Filename: "web.yaml", Content: []byte("kind: Service\n---\nkind: Deployment\n")},
+		{Filename: "empty.yaml", Content: []byte("\n")},
+		{Filename: "db.yaml", Content: []byte("kind: StatefulSet\n")},
+	}
+
+	var got []string
+	for _, split := range files.SplitSources() {
+		got = append(got, (*split)[0].Filename)
+	}
+
+	assert.Equal(t, []string{"web.yaml", "db.yaml"}, got)
+}
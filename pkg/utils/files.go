@@ -7,8 +7,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 )
@@ -102,3 +104,94 @@ func expand(g []string) ([]string, error) {
 
 	return matches, nil
 }
+
+// GlobFS adds double-star support to the core io/fs Glob function, expanding the
+// pattern over the provided file system.  A double-star matches any number of
+// directories, including none.
+func GlobFS(fsys fs.FS, pattern string) ([]string, error) {
+	if !strings.Contains(pattern, "**") {
+		// ensure the actual path exists if a glob pattern is not found
+		if !strings.Contains(pattern, "*") {
+			if _, err := fs.Stat(fsys, pattern); err != nil {
+				return nil, fmt.Errorf("%w; file %s cannot be found", err, pattern)
+			}
+		}
+
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return matches, fmt.Errorf("unable to expand glob, %w", err)
+		}
+
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("%w; unable to find any files from glob pattern %s", fs.ErrNotExist, pattern)
+		}
+
+		return matches, nil
+	}
+
+	segments := strings.Split(pattern, "/")
+
+	// validate the pattern up front as matching is only attempted on walked paths
+	for _, segment := range segments {
+		if _, err := path.Match(segment, ""); err != nil {
+			return nil, fmt.Errorf("unable to expand glob, %w", err)
+		}
+	}
+
+	var matches []string
+
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !entry.IsDir() && matchSegments(segments, strings.Split(name, "/")) {
+			matches = append(matches, name)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to expand glob, %w", err)
+	}
+
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("%w; unable to find any files from glob pattern %s", fs.ErrNotExist, pattern)
+	}
+
+	return matches, nil
+}
+
+// Match reports whether a slash separated path matches a glob pattern, adding
+// double-star support to the core path Match function.
+func Match(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// matchSegments determines if the segments of a path match the segments of a
+// pattern, where a double-star segment matches any number of path segments.
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if len(name) == 0 {
+		return false
+	}
+
+	if matched, _ := path.Match(pattern[0], name[0]); !matched {
+		return false
+	}
+
+	return matchSegments(pattern[1:], name[1:])
+}